@@ -0,0 +1,108 @@
+package db
+
+import (
+	"compatiblah/backend/models"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+func createCacheTable() error {
+	// LLM response cache: keyed by normalized MBTI pair, category, model and prompt
+	// version. Payloads have names replaced by placeholders, so no personal data is stored.
+	_, err := DB.Exec(`
+	CREATE TABLE IF NOT EXISTS llm_cache (
+		cache_key TEXT PRIMARY KEY,
+		type_pair TEXT NOT NULL,
+		category TEXT NOT NULL,
+		model TEXT NOT NULL,
+		prompt_version TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		expires_at INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_llm_cache_type_pair ON llm_cache (type_pair, category);
+	`)
+	return err
+}
+
+// GetCachedResponse returns the cached payload for key, or found=false if it is missing or expired
+func GetCachedResponse(key string) (string, bool, error) {
+	var payload string
+	err := DB.QueryRow(`
+	SELECT payload
+	FROM llm_cache
+	WHERE cache_key = ? AND (expires_at = 0 OR expires_at > ?)
+	`, key, time.Now().Unix()).Scan(&payload)
+
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	return payload, true, nil
+}
+
+// SaveCachedResponse inserts or replaces a cache entry
+func SaveCachedResponse(entry *models.CacheEntry) error {
+	var expiresAt int64
+	if !entry.ExpiresAt.IsZero() {
+		expiresAt = entry.ExpiresAt.Unix()
+	}
+
+	_, err := DB.Exec(`
+	INSERT OR REPLACE INTO llm_cache (
+		cache_key, type_pair, category, model, prompt_version, payload, expires_at
+	) VALUES (?, ?, ?, ?, ?, ?, ?)
+	`,
+		entry.Key,
+		entry.TypePair,
+		entry.Category,
+		entry.Model,
+		entry.PromptVersion,
+		entry.Payload,
+		expiresAt,
+	)
+
+	return err
+}
+
+// InvalidateCache deletes cache entries matching the given type pair and category.
+// Empty filters match everything. Returns the number of entries removed.
+func InvalidateCache(typePair, category string) (int64, error) {
+	conditions := []string{}
+	args := []interface{}{}
+
+	if typePair != "" {
+		conditions = append(conditions, "type_pair = ?")
+		args = append(args, typePair)
+	}
+	if category != "" {
+		conditions = append(conditions, "category = ?")
+		args = append(args, category)
+	}
+
+	query := "DELETE FROM llm_cache"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	result, err := DB.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// PurgeExpiredCache deletes entries whose TTL has elapsed
+func PurgeExpiredCache() (int64, error) {
+	result, err := DB.Exec(`DELETE FROM llm_cache WHERE expires_at != 0 AND expires_at <= ?`, time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -42,6 +42,60 @@ func InitDB(dbPath string) error {
 		return err
 	}
 
+	// Columns added after the original schema
+	if err := addColumnIfMissing("assessments", "prompt_version", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+	if err := addColumnIfMissing("assessments", "parent_id", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+	if err := addColumnIfMissing("assessments", "revision", "INTEGER NOT NULL DEFAULT 1"); err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+	if err := addColumnIfMissing("assessments", "language", "TEXT NOT NULL DEFAULT 'en'"); err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+	if err := addColumnIfMissing("assessments", "tone", "TEXT NOT NULL DEFAULT 'neutral'"); err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+	if err := addColumnIfMissing("assessments", "depth", "TEXT NOT NULL DEFAULT 'standard'"); err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+	if err := addColumnIfMissing("assessments", "weights_version", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+	if err := addColumnIfMissing("assessments", "score_details", "TEXT NOT NULL DEFAULT '{}'"); err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+
+	// Continuous 0-100 scores with confidence intervals. Older assessments are
+	// backfilled from their 1-5 score, with the interval its rounding covers.
+	for _, prefix := range []string{"friend", "coworker", "partner", "overall"} {
+		for _, column := range []string{"_precise_score", "_precise_low", "_precise_high"} {
+			if err := addColumnIfMissing("assessments", prefix+column, "REAL"); err != nil {
+				return fmt.Errorf("migration failed: %w", err)
+			}
+		}
+		_, err := DB.Exec(fmt.Sprintf(`
+			UPDATE assessments SET
+				%[1]s_precise_score = (%[1]s_score - 1) * 25.0,
+				%[1]s_precise_low = MAX(0, (%[1]s_score - 1.5) * 25.0),
+				%[1]s_precise_high = MIN(100, (%[1]s_score - 0.5) * 25.0)
+			WHERE %[1]s_precise_score IS NULL
+		`, prefix))
+		if err != nil {
+			return fmt.Errorf("migration failed: %w", err)
+		}
+	}
+
+	if err := createCacheTable(); err != nil {
+		return fmt.Errorf("failed to create cache table: %w", err)
+	}
+
+	if err := createUsageTable(); err != nil {
+		return fmt.Errorf("failed to create usage table: %w", err)
+	}
+
 	return nil
 }
 
@@ -112,15 +166,63 @@ func migrateIfNeeded() error {
 	return err
 }
 
+// addColumnIfMissing adds a column to an existing table unless it is already there.
+// definition is everything after the column name in ALTER TABLE ... ADD COLUMN.
+func addColumnIfMissing(table, column, definition string) error {
+	var exists bool
+	err := DB.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info(?)
+		WHERE name = ?
+	`, table, column).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return nil
+	}
+
+	_, err = DB.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
 func SaveAssessment(assessment *models.Assessment) error {
 	// Only save assessment results, NOT personal data (privacy-first approach)
 	query := `
 	INSERT INTO assessments (
 		id, friend_score, coworker_score, partner_score, overall_score,
-		friend_explanation, coworker_explanation, partner_explanation, created_at
-	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		friend_explanation, coworker_explanation, partner_explanation, created_at,
+		prompt_version, parent_id, revision, language, tone, depth, weights_version,
+		score_details,
+		friend_precise_score, friend_precise_low, friend_precise_high,
+		coworker_precise_score, coworker_precise_low, coworker_precise_high,
+		partner_precise_score, partner_precise_low, partner_precise_high,
+		overall_precise_score, overall_precise_low, overall_precise_high
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
+	revision := assessment.Revision
+	if revision < 1 {
+		revision = 1
+	}
+
+	language := assessment.Language
+	if language == "" {
+		language = "en"
+	}
+
+	tone := assessment.Tone
+	if tone == "" {
+		tone = "neutral"
+	}
+
+	depth := assessment.Depth
+	if depth == "" {
+		depth = "standard"
+	}
+
+	precise := assessment.PreciseScores
 	_, err := DB.Exec(query,
 		assessment.ID,
 		assessment.FriendScore,
@@ -131,6 +233,18 @@ func SaveAssessment(assessment *models.Assessment) error {
 		assessment.CoworkerExplanation,
 		assessment.PartnerExplanation,
 		assessment.CreatedAt,
+		assessment.PromptVersion,
+		assessment.ParentID,
+		revision,
+		language,
+		tone,
+		depth,
+		assessment.WeightsVersion,
+		assessment.ScoreDetails,
+		precise.Friend.Score, precise.Friend.Low, precise.Friend.High,
+		precise.Coworker.Score, precise.Coworker.Low, precise.Coworker.High,
+		precise.Partner.Score, precise.Partner.Low, precise.Partner.High,
+		precise.Overall.Score, precise.Overall.Low, precise.Overall.High,
 	)
 
 	return err
@@ -140,13 +254,20 @@ func GetAssessment(id string) (*models.Assessment, error) {
 	// Only retrieve assessment results, NOT personal data (privacy-first approach)
 	query := `
 	SELECT id, friend_score, coworker_score, partner_score, overall_score,
-		   friend_explanation, coworker_explanation, partner_explanation, created_at
+		   friend_explanation, coworker_explanation, partner_explanation, created_at,
+		   prompt_version, parent_id, revision, language, tone, depth, weights_version,
+		   score_details,
+		   friend_precise_score, friend_precise_low, friend_precise_high,
+		   coworker_precise_score, coworker_precise_low, coworker_precise_high,
+		   partner_precise_score, partner_precise_low, partner_precise_high,
+		   overall_precise_score, overall_precise_low, overall_precise_high
 	FROM assessments
 	WHERE id = ?
 	`
 
 	var assessment models.Assessment
 	var createdAt string
+	precise := &assessment.PreciseScores
 
 	err := DB.QueryRow(query, id).Scan(
 		&assessment.ID,
@@ -158,6 +279,18 @@ func GetAssessment(id string) (*models.Assessment, error) {
 		&assessment.CoworkerExplanation,
 		&assessment.PartnerExplanation,
 		&createdAt,
+		&assessment.PromptVersion,
+		&assessment.ParentID,
+		&assessment.Revision,
+		&assessment.Language,
+		&assessment.Tone,
+		&assessment.Depth,
+		&assessment.WeightsVersion,
+		&assessment.ScoreDetails,
+		&precise.Friend.Score, &precise.Friend.Low, &precise.Friend.High,
+		&precise.Coworker.Score, &precise.Coworker.Low, &precise.Coworker.High,
+		&precise.Partner.Score, &precise.Partner.Low, &precise.Partner.High,
+		&precise.Overall.Score, &precise.Overall.Low, &precise.Overall.High,
 	)
 
 	if err != nil {
@@ -179,7 +312,7 @@ func GetAssessment(id string) (*models.Assessment, error) {
 func GetAllAssessments() ([]*models.Assessment, error) {
 	// Privacy-first: only return assessment results, NOT personal data
 	query := `
-	SELECT id, overall_score, created_at
+	SELECT id, overall_score, overall_precise_score, overall_precise_low, overall_precise_high, created_at
 	FROM assessments
 	ORDER BY created_at DESC
 	LIMIT 100
@@ -199,6 +332,9 @@ func GetAllAssessments() ([]*models.Assessment, error) {
 		err := rows.Scan(
 			&assessment.ID,
 			&assessment.OverallScore,
+			&assessment.PreciseScores.Overall.Score,
+			&assessment.PreciseScores.Overall.Low,
+			&assessment.PreciseScores.Overall.High,
 			&createdAt,
 		)
 		if err != nil {
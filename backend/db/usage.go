@@ -0,0 +1,145 @@
+package db
+
+import (
+	"compatiblah/backend/models"
+	"strings"
+	"time"
+)
+
+func createUsageTable() error {
+	// One row per LLM call. assessment_id is filled in once the assessment is saved;
+	// calls for standalone category requests stay unattributed.
+	_, err := DB.Exec(`
+	CREATE TABLE IF NOT EXISTS llm_usage (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		assessment_id TEXT,
+		category TEXT NOT NULL,
+		provider TEXT NOT NULL,
+		model TEXT NOT NULL,
+		prompt_tokens INTEGER NOT NULL DEFAULT 0,
+		candidate_tokens INTEGER NOT NULL DEFAULT 0,
+		total_tokens INTEGER NOT NULL DEFAULT 0,
+		cost_usd REAL NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_llm_usage_assessment ON llm_usage (assessment_id);
+	CREATE INDEX IF NOT EXISTS idx_llm_usage_created_at ON llm_usage (created_at);
+	`)
+	return err
+}
+
+// SaveUsage records a single LLM call and returns its row ID
+func SaveUsage(record *models.UsageRecord) (int64, error) {
+	result, err := DB.Exec(`
+	INSERT INTO llm_usage (
+		assessment_id, category, provider, model,
+		prompt_tokens, candidate_tokens, total_tokens, cost_usd
+	) VALUES (NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?)
+	`,
+		record.AssessmentID,
+		record.Category,
+		record.Provider,
+		record.Model,
+		record.PromptTokens,
+		record.CandidateTokens,
+		record.TotalTokens,
+		record.CostUSD,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// AttributeUsage links previously saved usage rows to an assessment
+func AttributeUsage(ids []int64, assessmentID string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := []interface{}{assessmentID}
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args = append(args, id)
+	}
+
+	_, err := DB.Exec(
+		"UPDATE llm_usage SET assessment_id = ? WHERE id IN ("+strings.Join(placeholders, ", ")+")",
+		args...,
+	)
+	return err
+}
+
+// GetAssessmentUsage returns the LLM calls attributed to an assessment
+func GetAssessmentUsage(assessmentID string) ([]*models.UsageRecord, error) {
+	rows, err := DB.Query(`
+	SELECT assessment_id, category, provider, model,
+		prompt_tokens, candidate_tokens, total_tokens, cost_usd
+	FROM llm_usage
+	WHERE assessment_id = ?
+	ORDER BY id
+	`, assessmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*models.UsageRecord
+	for rows.Next() {
+		record := &models.UsageRecord{}
+		err := rows.Scan(
+			&record.AssessmentID,
+			&record.Category,
+			&record.Provider,
+			&record.Model,
+			&record.PromptTokens,
+			&record.CandidateTokens,
+			&record.TotalTokens,
+			&record.CostUSD,
+		)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+// GetUsageSummary aggregates usage per UTC day and model since the given time
+func GetUsageSummary(since time.Time) ([]*models.UsageSummary, error) {
+	rows, err := DB.Query(`
+	SELECT date(created_at) AS day, model, COUNT(*),
+		SUM(prompt_tokens), SUM(candidate_tokens), SUM(total_tokens), SUM(cost_usd)
+	FROM llm_usage
+	WHERE created_at >= ?
+	GROUP BY day, model
+	ORDER BY day DESC, model
+	`, since.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []*models.UsageSummary
+	for rows.Next() {
+		summary := &models.UsageSummary{}
+		err := rows.Scan(
+			&summary.Day,
+			&summary.Model,
+			&summary.Calls,
+			&summary.PromptTokens,
+			&summary.CandidateTokens,
+			&summary.TotalTokens,
+			&summary.CostUSD,
+		)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, rows.Err()
+}
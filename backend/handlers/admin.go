@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"compatiblah/backend/db"
+	"compatiblah/backend/services"
+	"crypto/subtle"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// RequireAdmin guards admin routes with the ADMIN_TOKEN environment variable,
+// sent by clients in the X-Admin-Token header. Admin routes are disabled when it is unset.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := os.Getenv("ADMIN_TOKEN")
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin endpoints are disabled"})
+			return
+		}
+
+		provided := c.GetHeader("X-Admin-Token")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid admin token"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// InvalidateCache deletes cached LLM responses. Optional query parameters
+// mbti1, mbti2 and category narrow what is removed.
+func InvalidateCache(c *gin.Context) {
+	removed, err := services.InvalidateCache(c.Query("mbti1"), c.Query("mbti2"), c.Query("category"))
+	if err != nil {
+		renderBadRequest(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"removed": removed})
+}
+
+// ReloadWeights reloads the scoring weights from SCORING_WEIGHTS_FILE. Invalid
+// weights are rejected and the active version is kept.
+func ReloadWeights(c *gin.Context) {
+	version, err := services.LoadWeightsFromEnv()
+	if err != nil {
+		log.Printf("Failed to reload scoring weights: %v", err)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":           err.Error(),
+			"code":            codeInvalidWeights,
+			"weights_version": services.WeightsVersion(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"weights_version": version})
+}
+
+// GetUsage reports LLM token usage and estimated cost per day and model.
+// The optional days query parameter sets the window (default 30).
+func GetUsage(c *gin.Context) {
+	days := 30
+	if raw := c.Query("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			renderBadRequest(c, "days must be a positive integer")
+			return
+		}
+		days = parsed
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	summaries, err := db.GetUsageSummary(since)
+	if err != nil {
+		renderInternalError(c, "Failed to retrieve usage", err)
+		return
+	}
+
+	totalTokens := 0
+	totalCost := 0.0
+	for _, summary := range summaries {
+		totalTokens += summary.TotalTokens
+		totalCost += summary.CostUSD
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"days":           days,
+		"total_tokens":   totalTokens,
+		"total_cost_usd": totalCost,
+		"daily":          summaries,
+	})
+}
+
+// GetAssessmentUsage lists the LLM calls made for one assessment with their cost
+func GetAssessmentUsage(c *gin.Context) {
+	records, err := db.GetAssessmentUsage(c.Param("id"))
+	if err != nil {
+		renderInternalError(c, "Failed to retrieve usage", err)
+		return
+	}
+
+	totalTokens := 0
+	totalCost := 0.0
+	for _, record := range records {
+		totalTokens += record.TotalTokens
+		totalCost += record.CostUSD
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"assessment_id":  c.Param("id"),
+		"calls":          records,
+		"total_tokens":   totalTokens,
+		"total_cost_usd": totalCost,
+	})
+}
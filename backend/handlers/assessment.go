@@ -12,25 +12,35 @@ import (
 func AssessCompatibility(c *gin.Context) {
 	var req models.AssessmentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		renderBadRequest(c, "Invalid request body: "+err.Error())
 		return
 	}
 
 	// Validate required fields
 	if req.Person1.Name == "" || req.Person1.MBTI == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Person 1 must have a name and MBTI type"})
+		renderBadRequest(c, "Person 1 must have a name and MBTI type")
 		return
 	}
 
 	if req.Person2.Name == "" || req.Person2.MBTI == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Person 2 must have a name and MBTI type"})
+		renderBadRequest(c, "Person 2 must have a name and MBTI type")
 		return
 	}
 
-	// Call Gemini API
-	geminiResp, err := services.AssessCompatibility(req.Person1, req.Person2)
+	if !normalizePeople(c, &req.Person1, &req.Person2) {
+		return
+	}
+
+	options := services.AssessmentOptions{Samples: req.Samples, Language: req.Language, Tone: req.Tone, Depth: req.Depth}
+	if !normalizeOptions(c, &options) {
+		return
+	}
+
+	// Call Gemini API (cancelled if the client disconnects)
+	ctx, usage := services.WithUsageTracker(c.Request.Context())
+	geminiResp, err := services.AssessCompatibility(ctx, req.Person1, req.Person2, options)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assess compatibility: " + err.Error()})
+		renderError(c, err)
 		return
 	}
 
@@ -44,16 +54,24 @@ func AssessCompatibility(c *gin.Context) {
 		FriendExplanation:   geminiResp.FriendExplanation,
 		CoworkerExplanation: geminiResp.CoworkerExplanation,
 		PartnerExplanation:  geminiResp.PartnerExplanation,
+		PromptVersion:       geminiResp.PromptVersion,
+		WeightsVersion:      geminiResp.WeightsVersion,
+		ScoreDetails:        geminiResp.ScoreDetails,
+		PreciseScores:       geminiResp.PreciseScores,
+		Language:            options.Language,
+		Tone:                options.Tone,
+		Depth:               options.Depth,
 	}
 
 	// Save to database (only assessment results, no personal data stored)
 	if err := db.SaveAssessment(assessment); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save assessment: " + err.Error()})
+		renderInternalError(c, "Failed to save assessment", err)
 		return
 	}
+	usage.Attribute(assessment.ID)
 
 	// Return response
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"id":                   assessment.ID,
 		"friend_score":         assessment.FriendScore,
 		"coworker_score":       assessment.CoworkerScore,
@@ -62,19 +80,32 @@ func AssessCompatibility(c *gin.Context) {
 		"friend_explanation":   assessment.FriendExplanation,
 		"coworker_explanation": assessment.CoworkerExplanation,
 		"partner_explanation":  assessment.PartnerExplanation,
-	})
+		"source":               geminiResp.Source,
+		"prompt_version":       assessment.PromptVersion,
+		"weights_version":      assessment.WeightsVersion,
+		"score_details":        assessment.ScoreDetails,
+		"precise_scores":       assessment.PreciseScores,
+		"language":             assessment.Language,
+		"tone":                 assessment.Tone,
+		"depth":                assessment.Depth,
+	}
+	if geminiResp.Consensus != nil {
+		response["consensus"] = geminiResp.Consensus
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 func GetAssessment(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Assessment ID is required"})
+		renderBadRequest(c, "Assessment ID is required")
 		return
 	}
 
 	assessment, err := db.GetAssessment(id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Assessment not found"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "Assessment not found", "code": codeNotFound})
 		return
 	}
 
@@ -89,13 +120,22 @@ func GetAssessment(c *gin.Context) {
 		"coworker_explanation": assessment.CoworkerExplanation,
 		"partner_explanation":  assessment.PartnerExplanation,
 		"created_at":           assessment.CreatedAt,
+		"prompt_version":       assessment.PromptVersion,
+		"parent_id":            assessment.ParentID,
+		"revision":             assessment.Revision,
+		"language":             assessment.Language,
+		"tone":                 assessment.Tone,
+		"depth":                assessment.Depth,
+		"weights_version":      assessment.WeightsVersion,
+		"score_details":        assessment.ScoreDetails,
+		"precise_scores":       assessment.PreciseScores,
 	})
 }
 
 func GetAllAssessments(c *gin.Context) {
 	assessments, err := db.GetAllAssessments()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve assessments: " + err.Error()})
+		renderInternalError(c, "Failed to retrieve assessments", err)
 		return
 	}
 
@@ -107,49 +147,74 @@ type CategoryAssessmentRequest struct {
 	Person1  models.PersonData `json:"person1"`
 	Person2  models.PersonData `json:"person2"`
 	Category string            `json:"category"` // "friend", "coworker", or "partner"
+	Samples  int               `json:"samples,omitempty"`
+	Language string            `json:"language,omitempty"` // BCP-47 tag, empty for English
+	Tone     string            `json:"tone,omitempty"`     // "playful", "neutral" or "professional"
+	Depth    string            `json:"depth,omitempty"`    // "summary", "standard" or "deep-dive"
 }
 
 func AssessCategory(c *gin.Context) {
 	var req CategoryAssessmentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		renderBadRequest(c, "Invalid request body: "+err.Error())
 		return
 	}
 
 	// Validate required fields
 	if req.Person1.Name == "" || req.Person1.MBTI == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Person 1 must have a name and MBTI type"})
+		renderBadRequest(c, "Person 1 must have a name and MBTI type")
 		return
 	}
 
 	if req.Person2.Name == "" || req.Person2.MBTI == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Person 2 must have a name and MBTI type"})
+		renderBadRequest(c, "Person 2 must have a name and MBTI type")
+		return
+	}
+
+	if !normalizePeople(c, &req.Person1, &req.Person2) {
 		return
 	}
 
 	// Validate category
 	if req.Category != "friend" && req.Category != "coworker" && req.Category != "partner" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Category must be 'friend', 'coworker', or 'partner'"})
+		renderBadRequest(c, "Category must be 'friend', 'coworker', or 'partner'")
 		return
 	}
 
-	// Call Gemini API for the requested category
+	options := services.AssessmentOptions{Samples: req.Samples, Language: req.Language, Tone: req.Tone, Depth: req.Depth}
+	if !normalizeOptions(c, &options) {
+		return
+	}
+
+	// Call Gemini API for the requested category (cancelled if the client disconnects)
 	categoryResp, err := services.AssessCategoryCompatibility(
+		c.Request.Context(),
 		req.Person1,
 		req.Person2,
 		req.Category,
+		options,
 	)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assess category compatibility: " + err.Error()})
+		renderError(c, err)
 		return
 	}
 
 	// Return enhanced response
 	response := gin.H{
-		"category":    req.Category,
-		"score":       categoryResp.Score,
-		"explanation": categoryResp.Explanation,
-		"source":      "gemini",
+		"category":        req.Category,
+		"score":           categoryResp.Score,
+		"explanation":     categoryResp.Explanation,
+		"source":          categoryResp.Source,
+		"prompt_version":  categoryResp.PromptVersion,
+		"weights_version": categoryResp.WeightsVersion,
+		"score_details":   categoryResp.ScoreDetails,
+		"precise_score":   categoryResp.PreciseScore,
+		"language":        options.Language,
+		"tone":            options.Tone,
+		"depth":           options.Depth,
+	}
+	if categoryResp.Consensus != nil {
+		response["consensus"] = categoryResp.Consensus
 	}
 
 	c.JSON(http.StatusOK, response)
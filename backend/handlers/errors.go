@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"compatiblah/backend/services"
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+)
+
+// Error codes produced by the handlers themselves; service failures use the
+// services.ErrorKind value as their code
+const (
+	codeInvalidRequest = "invalid_request"
+	codeNotFound       = "not_found"
+	codeTimeout        = "timeout"
+	codeInvalidWeights = "invalid_weights"
+)
+
+// errorStatus maps service error kinds to HTTP status codes
+func errorStatus(kind services.ErrorKind) int {
+	switch kind {
+	case services.ErrRateLimited:
+		return http.StatusTooManyRequests
+	case services.ErrUpstreamUnavailable:
+		return http.StatusServiceUnavailable
+	case services.ErrUpstreamRejected, services.ErrUnparseable:
+		return http.StatusBadGateway
+	case services.ErrSafetyBlocked, services.ErrInvalidInput:
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// errorBody is the JSON error payload: a client-safe message and a stable code
+func errorBody(err error) gin.H {
+	return gin.H{"error": services.PublicMessage(err), "code": string(services.KindOf(err))}
+}
+
+// renderError writes the response for a failed service call. The full error,
+// which may contain upstream response bodies, is only logged.
+func renderError(c *gin.Context, err error) {
+	if abortOnContextError(c, err) {
+		return
+	}
+
+	kind := services.KindOf(err)
+	log.Printf("%s %s failed (%s): %v", c.Request.Method, c.FullPath(), kind, err)
+	c.JSON(errorStatus(kind), errorBody(err))
+}
+
+// renderBadRequest rejects a malformed request
+func renderBadRequest(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, gin.H{"error": message, "code": codeInvalidRequest})
+}
+
+// renderInternalError logs err and responds with a generic 500
+func renderInternalError(c *gin.Context, message string, err error) {
+	log.Printf("%s %s failed: %s: %v", c.Request.Method, c.FullPath(), message, err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message, "code": string(services.ErrInternal)})
+}
+
+// abortOnContextError handles cancelled or timed-out assessments. It returns true
+// if the error came from the request context and a response has been written.
+func abortOnContextError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, context.Canceled):
+		// Client went away; nobody is listening for the response
+		log.Printf("Assessment cancelled by client: %v", err)
+		c.AbortWithStatus(499)
+		return true
+	case errors.Is(err, context.DeadlineExceeded):
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "Assessment timed out, please try again", "code": codeTimeout})
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"compatiblah/backend/models"
+	"compatiblah/backend/services"
+	"github.com/gin-gonic/gin"
+	"log"
+)
+
+// normalizePeople replaces both people with their normalized form. If either is
+// rejected the request is logged and answered, and false is returned.
+func normalizePeople(c *gin.Context, person1, person2 *models.PersonData) bool {
+	for i, person := range []*models.PersonData{person1, person2} {
+		normalized, err := services.NormalizePerson(*person, i+1)
+		if err != nil {
+			log.Printf("Rejected input from %s on %s: %v", c.ClientIP(), c.FullPath(), err)
+			c.JSON(errorStatus(services.KindOf(err)), errorBody(err))
+			return false
+		}
+		*person = normalized
+	}
+	return true
+}
+
+// normalizeOptions replaces the requested options with their validated form and
+// server defaults, so the stored assessment records what it was generated with.
+// If they are rejected the request is answered and false is returned.
+func normalizeOptions(c *gin.Context, options *services.AssessmentOptions) bool {
+	normalized, err := services.NormalizeOptions(*options)
+	if err != nil {
+		c.JSON(errorStatus(services.KindOf(err)), errorBody(err))
+		return false
+	}
+	*options = normalized
+	return true
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"compatiblah/backend/db"
+	"compatiblah/backend/models"
+	"compatiblah/backend/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"time"
+)
+
+// RefineAssessmentRequest asks for one category of a stored assessment to be
+// regenerated with additional context. The people are sent again because
+// assessments are stored without personal data.
+type RefineAssessmentRequest struct {
+	Person1  models.PersonData `json:"person1"`
+	Person2  models.PersonData `json:"person2"`
+	Category string            `json:"category"` // "friend", "coworker", or "partner"
+	Context  string            `json:"context"`
+}
+
+// RefineAssessment regenerates one category of a stored assessment using its
+// current explanation as the base plus the user's context, and saves the result
+// as a new revision with the same language, tone and depth
+func RefineAssessment(c *gin.Context) {
+	var req RefineAssessmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		renderBadRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if req.Person1.Name == "" || req.Person1.MBTI == "" {
+		renderBadRequest(c, "Person 1 must have a name and MBTI type")
+		return
+	}
+
+	if req.Person2.Name == "" || req.Person2.MBTI == "" {
+		renderBadRequest(c, "Person 2 must have a name and MBTI type")
+		return
+	}
+
+	if req.Category != "friend" && req.Category != "coworker" && req.Category != "partner" {
+		renderBadRequest(c, "Category must be 'friend', 'coworker', or 'partner'")
+		return
+	}
+
+	if !normalizePeople(c, &req.Person1, &req.Person2) {
+		return
+	}
+
+	parent, err := db.GetAssessment(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Assessment not found", "code": codeNotFound})
+		return
+	}
+
+	refined := *parent
+	score, explanation, precise := categoryFields(&refined, req.Category)
+	base := *explanation
+
+	options := services.AssessmentOptions{Language: parent.Language, Tone: parent.Tone, Depth: parent.Depth}
+	ctx, usage := services.WithUsageTracker(c.Request.Context())
+	categoryResp, err := services.AssessCategoryCompatibilityWithBase(ctx, req.Person1, req.Person2, req.Category, &base, req.Context, options)
+	if err != nil {
+		renderError(c, err)
+		return
+	}
+
+	if categoryResp.Source == services.SourceHeuristic {
+		// A heuristic result ignores the base and context, so it is not a refinement
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Refining needs the assessment service, which is temporarily unavailable",
+			"code":  string(services.ErrUpstreamUnavailable),
+		})
+		return
+	}
+
+	refined.ID = uuid.New().String()
+	refined.ParentID = parent.ID
+	refined.Revision = max(parent.Revision, 1) + 1
+	refined.PromptVersion = categoryResp.PromptVersion
+	// The other categories keep the parent's scores, so the assessment keeps the
+	// parent's weights version; score_details records each category's version
+	refined.ScoreDetails = make(models.ScoreBreakdown, len(parent.ScoreDetails)+1)
+	for category, details := range parent.ScoreDetails {
+		refined.ScoreDetails[category] = details
+	}
+	refined.ScoreDetails[req.Category] = categoryResp.ScoreDetails
+	refined.CreatedAt = time.Now()
+	*score = categoryResp.Score
+	*explanation = categoryResp.Explanation
+	*precise = categoryResp.PreciseScore
+	refined.OverallScore = services.OverallScore(refined.FriendScore, refined.CoworkerScore, refined.PartnerScore)
+	refined.PreciseScores.Overall = services.OverallPreciseScore(refined.PreciseScores.Friend, refined.PreciseScores.Coworker, refined.PreciseScores.Partner)
+
+	if err := db.SaveAssessment(&refined); err != nil {
+		renderInternalError(c, "Failed to save refined assessment", err)
+		return
+	}
+	usage.Attribute(refined.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":                 refined.ID,
+		"parent_id":          refined.ParentID,
+		"revision":           refined.Revision,
+		"category":           req.Category,
+		"old_scores":         scoreSet(parent),
+		"new_scores":         scoreSet(&refined),
+		"old_precise_scores": parent.PreciseScores,
+		"new_precise_scores": refined.PreciseScores,
+		"explanation":        categoryResp.Explanation,
+		"source":             categoryResp.Source,
+		"prompt_version":     refined.PromptVersion,
+		"weights_version":    categoryResp.WeightsVersion,
+		"score_details":      categoryResp.ScoreDetails,
+		"language":           refined.Language,
+		"tone":               refined.Tone,
+		"depth":              refined.Depth,
+	})
+}
+
+// categoryFields returns the score, explanation and precise score of assessment for category
+func categoryFields(assessment *models.Assessment, category string) (*int, *models.CategoryExplanation, *models.PreciseScore) {
+	switch category {
+	case "friend":
+		return &assessment.FriendScore, &assessment.FriendExplanation, &assessment.PreciseScores.Friend
+	case "coworker":
+		return &assessment.CoworkerScore, &assessment.CoworkerExplanation, &assessment.PreciseScores.Coworker
+	default:
+		return &assessment.PartnerScore, &assessment.PartnerExplanation, &assessment.PreciseScores.Partner
+	}
+}
+
+// scoreSet is the scores of an assessment as returned by RefineAssessment
+func scoreSet(assessment *models.Assessment) gin.H {
+	return gin.H{
+		"friend":   assessment.FriendScore,
+		"coworker": assessment.CoworkerScore,
+		"partner":  assessment.PartnerScore,
+		"overall":  assessment.OverallScore,
+	}
+}
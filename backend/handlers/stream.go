@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"compatiblah/backend/db"
+	"compatiblah/backend/models"
+	"compatiblah/backend/services"
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+)
+
+// streamCategories are assessed concurrently by AssessStream
+var streamCategories = []string{"friend", "coworker", "partner"}
+
+// streamMessage is one server-sent event queued by a category worker
+type streamMessage struct {
+	event    string
+	category string
+	data     gin.H
+	response *services.CategoryResponse
+	failed   bool
+}
+
+// AssessStream runs the three category assessments concurrently and reports
+// progress as server-sent events:
+//
+//	score     {category, score}                                                                         first event per category
+//	section   {category, index, section}                                                                each explanation section
+//	category  {category, score, explanation, source, score_details, precise_score}                      category finished
+//	error     {category, error, code}                                                                   category failed
+//	complete  {id, *_score, overall_score, precise_scores, prompt_version, weights_version, language}   terminal, assessment saved
+//	failed    {error, code}                                                                             terminal, nothing saved (first category error)
+func AssessStream(c *gin.Context) {
+	var req models.AssessmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		renderBadRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	// Validate required fields
+	if req.Person1.Name == "" || req.Person1.MBTI == "" {
+		renderBadRequest(c, "Person 1 must have a name and MBTI type")
+		return
+	}
+
+	if req.Person2.Name == "" || req.Person2.MBTI == "" {
+		renderBadRequest(c, "Person 2 must have a name and MBTI type")
+		return
+	}
+
+	if !normalizePeople(c, &req.Person1, &req.Person2) {
+		return
+	}
+
+	options := services.AssessmentOptions{Language: req.Language, Tone: req.Tone, Depth: req.Depth}
+	if !normalizeOptions(c, &options) {
+		return
+	}
+
+	// Workers stop when the client disconnects
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	ctx, usage := services.WithUsageTracker(ctx)
+	// All three categories are scored with one version of the weights
+	ctx = services.WithWeightsSnapshot(ctx)
+
+	messages := make(chan streamMessage)
+	send := func(msg streamMessage) {
+		select {
+		case messages <- msg:
+		case <-ctx.Done():
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, category := range streamCategories {
+		wg.Add(1)
+		go func(category string) {
+			defer wg.Done()
+
+			response, err := services.StreamCategoryCompatibility(ctx, req.Person1, req.Person2, category, options, func(event services.StreamEvent) {
+				switch event.Kind {
+				case services.StreamEventScore:
+					send(streamMessage{event: "score", data: gin.H{"category": category, "score": event.Score}})
+				case services.StreamEventSection:
+					send(streamMessage{event: "section", data: gin.H{"category": category, "index": event.Index, "section": event.Section}})
+				}
+			})
+			if err != nil {
+				log.Printf("Streamed %s assessment failed: %v", category, err)
+				data := errorBody(err)
+				data["category"] = category
+				send(streamMessage{event: "error", category: category, failed: true, data: data})
+				return
+			}
+
+			send(streamMessage{event: "category", category: category, response: response, data: gin.H{
+				"category":      category,
+				"score":         response.Score,
+				"explanation":   response.Explanation,
+				"source":        response.Source,
+				"score_details": response.ScoreDetails,
+				"precise_score": response.PreciseScore,
+			}})
+		}(category)
+	}
+
+	go func() {
+		wg.Wait()
+		close(messages)
+	}()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Stop reverse proxies from buffering the stream
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	responses := make(map[string]*services.CategoryResponse)
+	var failure gin.H
+	clientGone := c.Stream(func(w io.Writer) bool {
+		msg, ok := <-messages
+		if !ok {
+			return false
+		}
+		if msg.response != nil {
+			responses[msg.category] = msg.response
+		}
+		if msg.failed && failure == nil {
+			failure = gin.H{"error": msg.data["error"], "code": msg.data["code"]}
+		}
+		c.SSEvent(msg.event, msg.data)
+		return true
+	})
+	if clientGone {
+		log.Printf("Assessment stream cancelled by client")
+		return
+	}
+
+	if failure == nil && len(responses) != len(streamCategories) {
+		failure = gin.H{"error": "Failed to assess compatibility", "code": string(services.ErrInternal)}
+	}
+	if failure != nil {
+		c.SSEvent("failed", failure)
+		c.Writer.Flush()
+		return
+	}
+
+	friend, coworker, partner := responses["friend"], responses["coworker"], responses["partner"]
+	scoreDetails := models.ScoreBreakdown{
+		"friend":   friend.ScoreDetails,
+		"coworker": coworker.ScoreDetails,
+		"partner":  partner.ScoreDetails,
+	}
+	preciseScores := models.PreciseScores{
+		Friend:   friend.PreciseScore,
+		Coworker: coworker.PreciseScore,
+		Partner:  partner.PreciseScore,
+		Overall:  services.OverallPreciseScore(friend.PreciseScore, coworker.PreciseScore, partner.PreciseScore),
+	}
+
+	// Create assessment record (privacy-first: only save results, NOT personal data)
+	assessment := &models.Assessment{
+		ID:                  uuid.New().String(),
+		FriendScore:         friend.Score,
+		CoworkerScore:       coworker.Score,
+		PartnerScore:        partner.Score,
+		OverallScore:        services.OverallScore(friend.Score, coworker.Score, partner.Score),
+		FriendExplanation:   friend.Explanation,
+		CoworkerExplanation: coworker.Explanation,
+		PartnerExplanation:  partner.Explanation,
+		PromptVersion:       streamPromptVersion(friend, coworker, partner),
+		WeightsVersion:      friend.WeightsVersion,
+		ScoreDetails:        scoreDetails,
+		PreciseScores:       preciseScores,
+		Language:            options.Language,
+		Tone:                options.Tone,
+		Depth:               options.Depth,
+	}
+
+	if err := db.SaveAssessment(assessment); err != nil {
+		log.Printf("Failed to save streamed assessment: %v", err)
+		c.SSEvent("failed", gin.H{"error": "Failed to save assessment", "code": string(services.ErrInternal)})
+		c.Writer.Flush()
+		return
+	}
+	usage.Attribute(assessment.ID)
+
+	c.SSEvent("complete", gin.H{
+		"id":              assessment.ID,
+		"friend_score":    assessment.FriendScore,
+		"coworker_score":  assessment.CoworkerScore,
+		"partner_score":   assessment.PartnerScore,
+		"overall_score":   assessment.OverallScore,
+		"precise_scores":  assessment.PreciseScores,
+		"prompt_version":  assessment.PromptVersion,
+		"weights_version": assessment.WeightsVersion,
+		"language":        assessment.Language,
+		"tone":            assessment.Tone,
+		"depth":           assessment.Depth,
+	})
+	c.Writer.Flush()
+}
+
+// streamPromptVersion returns the prompt version shared by the category results;
+// heuristic results have none, so the first non-empty version wins
+func streamPromptVersion(responses ...*services.CategoryResponse) string {
+	for _, response := range responses {
+		if response.PromptVersion != "" {
+			return response.PromptVersion
+		}
+	}
+	return ""
+}
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"expvar"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 	"compatiblah/backend/db"
 	"compatiblah/backend/handlers"
+	"compatiblah/backend/services"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
@@ -18,10 +22,49 @@ func main() {
 	}
 	log.Println("Database initialized successfully")
 
-	// Check for Gemini API key
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		log.Fatal("GEMINI_API_KEY environment variable is not set")
+	// Drop LLM cache entries whose TTL has elapsed
+	if purged, err := db.PurgeExpiredCache(); err != nil {
+		log.Printf("Failed to purge expired cache entries: %v", err)
+	} else if purged > 0 {
+		log.Printf("Purged %d expired cache entries", purged)
+	}
+
+	// Load prompt templates (PROMPT_DIR overrides the embedded ones)
+	if err := services.LoadPromptsFromEnv(); err != nil {
+		log.Fatalf("Failed to load prompt templates: %v", err)
+	}
+
+	// Load scoring weights (SCORING_WEIGHTS_FILE overrides the embedded ones)
+	if _, err := services.LoadWeightsFromEnv(); err != nil {
+		log.Fatalf("Failed to load scoring weights: %v", err)
+	}
+
+	// Select the heuristic scoring model (SCORING_MODEL)
+	if err := services.LoadScoringModelFromEnv(); err != nil {
+		log.Fatalf("Failed to configure scoring model: %v", err)
+	}
+
+	// Reload scoring weights on SIGHUP, keeping the current ones if the file is invalid
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		for range hangup {
+			if _, err := services.LoadWeightsFromEnv(); err != nil {
+				log.Printf("Failed to reload scoring weights: %v", err)
+			}
+		}
+	}()
+
+	// Configure LLM provider (LLM_PROVIDER selects gemini, openai, ollama or offline)
+	provider, err := services.NewProviderFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure LLM provider: %v", err)
+	}
+	services.SetProvider(provider)
+	if provider == nil {
+		log.Println("No LLM provider configured, serving offline heuristic assessments")
+	} else {
+		log.Printf("Using LLM provider %s (model %s)", provider.Name(), provider.Model())
 	}
 
 	// Setup Gin router
@@ -82,13 +125,25 @@ func main() {
 	{
 		api.POST("/assess", handlers.AssessCompatibility)
 		api.POST("/assess/category", handlers.AssessCategory)
+		api.POST("/assess/stream", handlers.AssessStream)
 		api.GET("/assessment/:id", handlers.GetAssessment)
+		api.POST("/assessment/:id/refine", handlers.RefineAssessment)
 		api.GET("/assessments", handlers.GetAllAssessments)
 	}
 
+	// Admin routes (require X-Admin-Token matching ADMIN_TOKEN)
+	admin := api.Group("/admin", handlers.RequireAdmin())
+	{
+		admin.DELETE("/cache", handlers.InvalidateCache)
+		admin.GET("/metrics", gin.WrapH(expvar.Handler()))
+		admin.GET("/usage", handlers.GetUsage)
+		admin.GET("/usage/:id", handlers.GetAssessmentUsage)
+		admin.POST("/weights/reload", handlers.ReloadWeights)
+	}
+
 	// Health check
 	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
+		c.JSON(200, gin.H{"status": "ok", "llm": services.ProviderStatus()})
 	})
 
 	// Root endpoint - helpful message
@@ -99,7 +154,9 @@ func main() {
 			"endpoints": gin.H{
 				"health": "/health",
 				"assess": "POST /api/assess",
+				"assess_stream": "POST /api/assess/stream",
 				"get_assessment": "GET /api/assessment/:id",
+				"refine_assessment": "POST /api/assessment/:id/refine",
 				"get_all": "GET /api/assessments",
 			},
 		})
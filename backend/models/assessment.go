@@ -25,11 +25,35 @@ type Assessment struct {
 	CoworkerExplanation CategoryExplanation `json:"coworker_explanation" db:"coworker_explanation"`
 	PartnerExplanation  CategoryExplanation `json:"partner_explanation" db:"partner_explanation"`
 	CreatedAt           time.Time           `json:"created_at" db:"created_at"`
+	PromptVersion       string              `json:"prompt_version" db:"prompt_version"`
+	// ParentID is the assessment this one refines, empty for an original assessment
+	ParentID string `json:"parent_id" db:"parent_id"`
+	// Revision counts refinements: 1 for an original assessment
+	Revision int `json:"revision" db:"revision"`
+	// Language is the language code the explanations are written in
+	Language string `json:"language" db:"language"`
+	// Tone and Depth are the style options the explanations were generated with
+	Tone  string `json:"tone" db:"tone"`
+	Depth string `json:"depth" db:"depth"`
+	// WeightsVersion identifies the scoring weights the scores were computed with
+	WeightsVersion string `json:"weights_version" db:"weights_version"`
+	// ScoreDetails explains each category score
+	ScoreDetails ScoreBreakdown `json:"score_details" db:"score_details"`
+	// PreciseScores are the continuous 0-100 scores behind the 1-5 scores
+	PreciseScores PreciseScores `json:"precise_scores"`
 }
 
 type AssessmentRequest struct {
 	Person1 PersonData `json:"person1"`
 	Person2 PersonData `json:"person2"`
+	// Samples is the number of model samples to combine, 0 for the server default
+	Samples int `json:"samples,omitempty"`
+	// Language is a BCP-47 tag for the explanations, empty for English
+	Language string `json:"language,omitempty"`
+	// Tone is "playful", "neutral" or "professional", empty for neutral
+	Tone string `json:"tone,omitempty"`
+	// Depth is "summary", "standard" or "deep-dive", empty for standard
+	Depth string `json:"depth,omitempty"`
 }
 
 type BulletPoint struct {
@@ -50,6 +74,56 @@ type CategoryExplanation struct {
 	Sections []ExplanationSection `json:"sections"`
 }
 
+// ScoreDetails breaks a category score down into what produced it
+type ScoreDetails struct {
+	// Model is the heuristic scoring model, "letters" or "functions", or
+	// "neutral" when a type could not be parsed
+	Model string `json:"model"`
+	// Base is the neutral score the contributions adjust
+	Base          float64             `json:"base"`
+	Contributions []ScoreContribution `json:"contributions"`
+	// Noise is the seeded jitter added before rounding
+	Noise          float64 `json:"noise"`
+	HeuristicScore int     `json:"heuristic_score"`
+	// LLMScore is the model score used in the blend, absent for heuristic-only results
+	LLMScore int         `json:"llm_score,omitempty"`
+	Blend    *ScoreBlend `json:"blend,omitempty"`
+	// WeightsVersion identifies the scoring weights of this category, which can
+	// differ from the assessment's after a refinement
+	WeightsVersion string `json:"weights_version,omitempty"`
+}
+
+// ScoreContribution is one dimension's adjustment to the heuristic score,
+// e.g. energy for the letters model
+type ScoreContribution struct {
+	Dimension string  `json:"dimension"`
+	Value     float64 `json:"value"`
+}
+
+// ScoreBlend is how a category score splits between the LLM and heuristic scores
+type ScoreBlend struct {
+	LLMWeight       float64 `json:"llm_weight"`
+	HeuristicWeight float64 `json:"heuristic_weight"`
+}
+
+// PreciseScore is a continuous 0-100 score with a confidence interval
+type PreciseScore struct {
+	Score float64 `json:"score"`
+	Low   float64 `json:"low"`
+	High  float64 `json:"high"`
+}
+
+// PreciseScores are the PreciseScore of each category and overall
+type PreciseScores struct {
+	Friend   PreciseScore `json:"friend"`
+	Coworker PreciseScore `json:"coworker"`
+	Partner  PreciseScore `json:"partner"`
+	Overall  PreciseScore `json:"overall"`
+}
+
+// ScoreBreakdown holds the ScoreDetails of each category, keyed by category name
+type ScoreBreakdown map[string]ScoreDetails
+
 type GeminiResponse struct {
 	FriendScore         int                 `json:"friend_score"`
 	CoworkerScore       int                 `json:"coworker_score"`
@@ -93,3 +167,55 @@ func (c *CategoryExplanation) Scan(value interface{}) error {
 	}
 	return json.Unmarshal(bytes, c)
 }
+
+// Value implements driver.Valuer for ScoreBreakdown
+func (b ScoreBreakdown) Value() (driver.Value, error) {
+	return json.Marshal(b)
+}
+
+// Scan implements sql.Scanner for ScoreBreakdown
+func (b *ScoreBreakdown) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, b)
+}
+
+// CacheEntry is a cached LLM response for a normalized MBTI pair and category
+type CacheEntry struct {
+	Key           string    `json:"key"`
+	TypePair      string    `json:"type_pair"`
+	Category      string    `json:"category"`
+	Model         string    `json:"model"`
+	PromptVersion string    `json:"prompt_version"`
+	Payload       string    `json:"payload"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// UsageRecord is the token usage and estimated cost of a single LLM call.
+// AssessmentID is empty until the call is attributed to a saved assessment.
+type UsageRecord struct {
+	AssessmentID    string  `json:"assessment_id,omitempty"`
+	Category        string  `json:"category"`
+	Provider        string  `json:"provider"`
+	Model           string  `json:"model"`
+	PromptTokens    int     `json:"prompt_tokens"`
+	CandidateTokens int     `json:"candidate_tokens"`
+	TotalTokens     int     `json:"total_tokens"`
+	CostUSD         float64 `json:"cost_usd"`
+}
+
+// UsageSummary aggregates LLM usage for one day and model
+type UsageSummary struct {
+	Day             string  `json:"day"`
+	Model           string  `json:"model"`
+	Calls           int     `json:"calls"`
+	PromptTokens    int     `json:"prompt_tokens"`
+	CandidateTokens int     `json:"candidate_tokens"`
+	TotalTokens     int     `json:"total_tokens"`
+	CostUSD         float64 `json:"cost_usd"`
+}
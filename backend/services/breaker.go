@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned instead of calling the provider while the circuit
+// breaker is open. Assessments fall back to heuristic-only results when they see it.
+var ErrCircuitOpen = errors.New("LLM provider temporarily unavailable (circuit open)")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitBreaker opens after a run of consecutive failures, rejects calls for a
+// cooldown period, then lets single probe calls through until enough succeed.
+type circuitBreaker struct {
+	mu               sync.Mutex
+	state            breakerState
+	failures         int
+	probeSuccesses   int
+	probeInFlight    bool
+	openedAt         time.Time
+	failureThreshold int
+	openDuration     time.Duration
+	probesToClose    int
+}
+
+// newCircuitBreakerFromEnv reads BREAKER_FAILURE_THRESHOLD, BREAKER_OPEN_DURATION
+// and BREAKER_PROBE_SUCCESSES
+func newCircuitBreakerFromEnv() *circuitBreaker {
+	threshold := envInt("BREAKER_FAILURE_THRESHOLD", 5)
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	probes := envInt("BREAKER_PROBE_SUCCESSES", 2)
+	if probes < 1 {
+		probes = 1
+	}
+
+	return &circuitBreaker{
+		failureThreshold: threshold,
+		openDuration:     envDuration("BREAKER_OPEN_DURATION", 30*time.Second),
+		probesToClose:    probes,
+	}
+}
+
+// allow reports whether a call may proceed, moving an expired open breaker to half-open
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.openDuration {
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.probeSuccesses = 0
+		b.probeInFlight = true
+		log.Printf("LLM circuit breaker half-open, probing provider")
+		return true
+	case breakerHalfOpen:
+		// Only one probe at a time; everyone else stays degraded
+		if b.probeInFlight {
+			return false
+		}
+		b.probeInFlight = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *circuitBreaker) recordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	if b.state != breakerHalfOpen {
+		return
+	}
+
+	b.probeInFlight = false
+	b.probeSuccesses++
+	if b.probeSuccesses >= b.probesToClose {
+		b.state = breakerClosed
+		log.Printf("LLM circuit breaker closed after %d successful probes", b.probeSuccesses)
+	}
+}
+
+func (b *circuitBreaker) recordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerHalfOpen:
+		b.probeInFlight = false
+		b.trip()
+	case breakerClosed:
+		b.failures++
+		if b.failures >= b.failureThreshold {
+			b.trip()
+		}
+	}
+}
+
+// trip opens the breaker; callers must hold b.mu
+func (b *circuitBreaker) trip() {
+	b.state = breakerOpen
+	b.openedAt = time.Now()
+	b.failures = 0
+	incMetric("breaker_opened")
+	log.Printf("LLM circuit breaker opened for %s", b.openDuration)
+}
+
+// release frees a probe slot without recording an outcome, e.g. when the caller
+// cancelled before the provider answered
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerHalfOpen {
+		b.probeInFlight = false
+	}
+}
+
+func (b *circuitBreaker) currentState() breakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// breakerProvider guards an LLMProvider with a circuit breaker
+type breakerProvider struct {
+	LLMProvider
+	breaker *circuitBreaker
+}
+
+func (p *breakerProvider) Generate(ctx context.Context, req GenerationRequest) (*GenerationResult, error) {
+	if !p.breaker.allow() {
+		incMetric("breaker_rejected")
+		return nil, ErrCircuitOpen
+	}
+
+	result, err := p.LLMProvider.Generate(ctx, req)
+	p.record(err)
+	return result, err
+}
+
+// GenerateStream streams through the wrapped provider under the same breaker
+func (p *breakerProvider) GenerateStream(ctx context.Context, req GenerationRequest, onChunk func(string)) (*GenerationResult, error) {
+	if !p.breaker.allow() {
+		incMetric("breaker_rejected")
+		return nil, ErrCircuitOpen
+	}
+
+	result, err := streamFrom(ctx, p.LLMProvider, req, onChunk)
+	p.record(err)
+	return result, err
+}
+
+// record feeds the outcome of an allowed call back into the breaker
+func (p *breakerProvider) record(err error) {
+	switch {
+	case err == nil:
+		p.breaker.recordSuccess()
+	case KindOf(err) == ErrSafetyBlocked:
+		// The provider answered; the content was the problem, not its health
+		p.breaker.recordSuccess()
+	case errors.Is(err, context.Canceled):
+		// The client gave up; that says nothing about provider health
+		p.breaker.release()
+	default:
+		p.breaker.recordFailure()
+	}
+}
+
+// ProviderStatus reports the circuit breaker state of the active provider:
+// "closed", "open", "half-open", or "offline" when no provider is configured
+func ProviderStatus() string {
+	provider, err := currentProvider()
+	if err != nil {
+		return "offline"
+	}
+
+	if guarded, ok := provider.(*breakerProvider); ok {
+		return guarded.breaker.currentState().String()
+	}
+	return breakerClosed.String()
+}
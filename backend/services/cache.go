@@ -0,0 +1,354 @@
+package services
+
+import (
+	"compatiblah/backend/db"
+	"compatiblah/backend/models"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Placeholders stored in cached text instead of names. personA is whoever has the
+// alphabetically first MBTI type, so A-vs-B and B-vs-A share one cache entry.
+const (
+	personAPlaceholder = "{{person_a}}"
+	personBPlaceholder = "{{person_b}}"
+)
+
+// responseCacheTTL is how long cached LLM responses stay valid (LLM_CACHE_TTL).
+// Zero disables the cache.
+func responseCacheTTL() time.Duration {
+	return envDuration("LLM_CACHE_TTL", 7*24*time.Hour)
+}
+
+// responseCache reads and writes cached LLM payloads for one pair, category and
+// set of output options (language, tone and depth).
+// A nil *responseCache is valid and never hits.
+type responseCache struct {
+	key           string
+	typePair      string
+	category      string
+	model         string
+	promptVersion string
+	nameA         string
+	nameB         string
+}
+
+// newResponseCache returns the cache slot for the request, or nil if caching is
+// disabled or the inputs cannot be normalized
+func newResponseCache(person1, person2 models.PersonData, category string, options AssessmentOptions) *responseCache {
+	if db.DB == nil || responseCacheTTL() <= 0 {
+		return nil
+	}
+
+	provider, err := currentProvider()
+	if err != nil {
+		return nil
+	}
+
+	typePair, swapped, ok := normalizeTypePair(person1.MBTI, person2.MBTI)
+	if !ok {
+		return nil
+	}
+
+	nameA, nameB := person1.Name, person2.Name
+	if swapped {
+		nameA, nameB = nameB, nameA
+	}
+
+	model := provider.Name() + "/" + provider.Model()
+	version := promptVersion(promptNameFor(category))
+
+	return &responseCache{
+		key:           strings.Join([]string{typePair, category, model, version, options.Language, options.Tone, options.Depth}, "|"),
+		typePair:      typePair,
+		category:      category,
+		model:         model,
+		promptVersion: version,
+		nameA:         strings.TrimSpace(nameA),
+		nameB:         strings.TrimSpace(nameB),
+	}
+}
+
+// normalizeTypePair returns "TYPE1-TYPE2" in alphabetical order and whether the
+// inputs were swapped to get there. ok is false for invalid MBTI types.
+func normalizeTypePair(mbti1, mbti2 string) (string, bool, bool) {
+	type1 := strings.ToUpper(strings.TrimSpace(mbti1))
+	type2 := strings.ToUpper(strings.TrimSpace(mbti2))
+
+	if _, ok := parseMBTIProfile(type1); !ok {
+		return "", false, false
+	}
+	if _, ok := parseMBTIProfile(type2); !ok {
+		return "", false, false
+	}
+
+	if type1 > type2 {
+		return type2 + "-" + type1, true, true
+	}
+	return type1 + "-" + type2, false, true
+}
+
+func (rc *responseCache) loadCategory() (*categoryPayload, bool) {
+	var payload categoryPayload
+	if !rc.load(&payload) {
+		return nil, false
+	}
+
+	rewriteExplanation(&payload.Explanation, rc.fillNames)
+	return &payload, true
+}
+
+func (rc *responseCache) storeCategory(payload *categoryPayload) {
+	if rc == nil {
+		return
+	}
+
+	templated := *payload
+	templated.Explanation = copyExplanation(payload.Explanation)
+	rewriteExplanation(&templated.Explanation, rc.templateNames)
+	if rc.mentionsNames(templated.Explanation) {
+		log.Printf("Not caching %s: names remain after templating", rc.key)
+		return
+	}
+	rc.store(templated)
+}
+
+func (rc *responseCache) loadAssessment() (*models.GeminiResponse, bool) {
+	var result models.GeminiResponse
+	if !rc.load(&result) {
+		return nil, false
+	}
+
+	rewriteExplanation(&result.FriendExplanation, rc.fillNames)
+	rewriteExplanation(&result.CoworkerExplanation, rc.fillNames)
+	rewriteExplanation(&result.PartnerExplanation, rc.fillNames)
+	return &result, true
+}
+
+func (rc *responseCache) storeAssessment(result *models.GeminiResponse) {
+	if rc == nil {
+		return
+	}
+
+	templated := *result
+	templated.FriendExplanation = copyExplanation(result.FriendExplanation)
+	templated.CoworkerExplanation = copyExplanation(result.CoworkerExplanation)
+	templated.PartnerExplanation = copyExplanation(result.PartnerExplanation)
+	rewriteExplanation(&templated.FriendExplanation, rc.templateNames)
+	rewriteExplanation(&templated.CoworkerExplanation, rc.templateNames)
+	rewriteExplanation(&templated.PartnerExplanation, rc.templateNames)
+	if rc.mentionsNames(templated.FriendExplanation, templated.CoworkerExplanation, templated.PartnerExplanation) {
+		log.Printf("Not caching %s: names remain after templating", rc.key)
+		return
+	}
+	rc.store(templated)
+}
+
+func (rc *responseCache) load(target interface{}) bool {
+	if rc == nil {
+		return false
+	}
+
+	payload, found, err := db.GetCachedResponse(rc.key)
+	if err != nil {
+		log.Printf("LLM cache lookup failed for %s: %v", rc.key, err)
+		return false
+	}
+	if !found {
+		return false
+	}
+
+	if err := json.Unmarshal([]byte(payload), target); err != nil {
+		log.Printf("Discarding unreadable LLM cache entry %s: %v", rc.key, err)
+		return false
+	}
+
+	return true
+}
+
+func (rc *responseCache) store(value interface{}) {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("Failed to encode LLM cache entry %s: %v", rc.key, err)
+		return
+	}
+
+	entry := &models.CacheEntry{
+		Key:           rc.key,
+		TypePair:      rc.typePair,
+		Category:      rc.category,
+		Model:         rc.model,
+		PromptVersion: rc.promptVersion,
+		Payload:       string(payload),
+		ExpiresAt:     time.Now().Add(responseCacheTTL()),
+	}
+
+	// Cache failures never fail the assessment
+	if err := db.SaveCachedResponse(entry); err != nil {
+		log.Printf("Failed to save LLM cache entry %s: %v", rc.key, err)
+	}
+}
+
+// templateNames replaces the people's names with placeholders before caching
+func (rc *responseCache) templateNames(text string) string {
+	// Replace the longer name first so one name containing the other is handled
+	first, firstPlaceholder := rc.nameA, personAPlaceholder
+	second, secondPlaceholder := rc.nameB, personBPlaceholder
+	if len(second) > len(first) {
+		first, second = second, first
+		firstPlaceholder, secondPlaceholder = secondPlaceholder, firstPlaceholder
+	}
+
+	text = replaceName(text, first, firstPlaceholder)
+	return replaceName(text, second, secondPlaceholder)
+}
+
+// mentionsNames reports whether any explanation still contains either name in
+// any casing, e.g. "ann" after templating "Ann", so a templating miss never
+// stores personal data in the shared cache
+func (rc *responseCache) mentionsNames(explanations ...models.CategoryExplanation) bool {
+	patterns := []*regexp.Regexp{}
+	for _, name := range []string{rc.nameA, rc.nameB} {
+		if re := namePattern(name, true); re != nil {
+			patterns = append(patterns, re)
+		}
+	}
+
+	for _, explanation := range explanations {
+		for _, text := range explanationTexts(explanation) {
+			for _, re := range patterns {
+				if re.MatchString(text) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// fillNames substitutes the current request's names back into cached text
+func (rc *responseCache) fillNames(text string) string {
+	text = strings.ReplaceAll(text, personAPlaceholder, rc.nameA)
+	return strings.ReplaceAll(text, personBPlaceholder, rc.nameB)
+}
+
+// replaceName replaces whole-word occurrences of name, including possessives like
+// "Sam's". The case must match so ordinary words such as "will" are left alone.
+func replaceName(text, name, placeholder string) string {
+	re := namePattern(name, false)
+	if re == nil {
+		return text
+	}
+	return re.ReplaceAllLiteralString(text, placeholder)
+}
+
+// namePattern matches name as a whole word, in any casing if ignoreCase is set;
+// nil for an empty name
+func namePattern(name string, ignoreCase bool) *regexp.Regexp {
+	if name == "" {
+		return nil
+	}
+
+	pattern := regexp.QuoteMeta(name)
+	if isWordRune(firstRune(name)) {
+		pattern = `\b` + pattern
+	}
+	if isWordRune(lastRune(name)) {
+		pattern += `\b`
+	}
+
+	if ignoreCase {
+		pattern = `(?i)` + pattern
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil
+	}
+	return re
+}
+
+func firstRune(s string) rune {
+	for _, r := range s {
+		return r
+	}
+	return 0
+}
+
+func lastRune(s string) rune {
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return 0
+	}
+	return runes[len(runes)-1]
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || (r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)))
+}
+
+// rewriteExplanation applies fn to every heading, title and bullet in place
+func rewriteExplanation(explanation *models.CategoryExplanation, fn func(string) string) {
+	for i := range explanation.Sections {
+		section := &explanation.Sections[i]
+		section.Heading = fn(section.Heading)
+		for j := range section.Subcategories {
+			subcategory := &section.Subcategories[j]
+			subcategory.Title = fn(subcategory.Title)
+			for k := range subcategory.Bullets {
+				subcategory.Bullets[k].Text = fn(subcategory.Bullets[k].Text)
+			}
+		}
+	}
+}
+
+// explanationTexts lists every heading, title and bullet of an explanation
+func explanationTexts(explanation models.CategoryExplanation) []string {
+	texts := []string{}
+	for _, section := range explanation.Sections {
+		texts = append(texts, section.Heading)
+		for _, subcategory := range section.Subcategories {
+			texts = append(texts, subcategory.Title)
+			for _, bullet := range subcategory.Bullets {
+				texts = append(texts, bullet.Text)
+			}
+		}
+	}
+	return texts
+}
+
+// copyExplanation deep-copies an explanation so it can be rewritten without
+// mutating the caller's value
+func copyExplanation(explanation models.CategoryExplanation) models.CategoryExplanation {
+	sections := make([]models.ExplanationSection, len(explanation.Sections))
+	for i, section := range explanation.Sections {
+		subcategories := make([]models.SubCategory, len(section.Subcategories))
+		for j, subcategory := range section.Subcategories {
+			bullets := make([]models.BulletPoint, len(subcategory.Bullets))
+			copy(bullets, subcategory.Bullets)
+			subcategories[j] = models.SubCategory{Title: subcategory.Title, Bullets: bullets}
+		}
+		sections[i] = models.ExplanationSection{Heading: section.Heading, Subcategories: subcategories}
+	}
+	return models.CategoryExplanation{Sections: sections}
+}
+
+// InvalidateCache removes cached LLM responses. mbti1/mbti2 and category narrow
+// the deletion; leaving them empty clears the whole cache.
+func InvalidateCache(mbti1, mbti2, category string) (int64, error) {
+	typePair := ""
+	if mbti1 != "" || mbti2 != "" {
+		pair, _, ok := normalizeTypePair(mbti1, mbti2)
+		if !ok {
+			return 0, fmt.Errorf("invalid MBTI type pair %q/%q", mbti1, mbti2)
+		}
+		typePair = pair
+	}
+
+	return db.InvalidateCache(typePair, category)
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"compatiblah/backend/models"
+	"context"
+	"strings"
+	"sync"
+)
+
+// coalescer collapses concurrent calls with the same key into a single execution
+// whose result is shared by every caller. The shared call is cancelled only once
+// all callers waiting on it have gone away.
+type coalescer struct {
+	mu    sync.Mutex
+	calls map[string]*inflightCall
+}
+
+type inflightCall struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+	value   interface{}
+	err     error
+}
+
+// assessmentCoalescer shares in-flight LLM assessments between identical requests
+var assessmentCoalescer = &coalescer{calls: map[string]*inflightCall{}}
+
+// do runs fn once per key among concurrent callers. shared reports whether this
+// caller received the result of a call started by someone else.
+func (g *coalescer) do(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (value interface{}, err error, shared bool) {
+	g.mu.Lock()
+	call, inflight := g.calls[key]
+	if inflight {
+		call.waiters++
+		g.mu.Unlock()
+		incMetric("coalesce_shared")
+	} else {
+		// Detach from the first caller's cancellation; the call lives while anyone waits
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &inflightCall{done: make(chan struct{}), cancel: cancel, waiters: 1}
+		g.calls[key] = call
+		g.mu.Unlock()
+		incMetric("coalesce_leader")
+
+		go func() {
+			defer cancel()
+			call.value, call.err = fn(callCtx)
+
+			g.mu.Lock()
+			if g.calls[key] == call {
+				delete(g.calls, key)
+			}
+			g.mu.Unlock()
+			close(call.done)
+		}()
+	}
+
+	select {
+	case <-call.done:
+		return call.value, call.err, inflight
+	case <-ctx.Done():
+		g.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			// Nobody is left to receive the result; stop the upstream call and
+			// let the next request start a fresh one
+			call.cancel()
+			if g.calls[key] == call {
+				delete(g.calls, key)
+			}
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err(), inflight
+	}
+}
+
+// coalesceKey builds the key identifying identical assessment requests
+func coalesceKey(kind, category string, person1, person2 models.PersonData, options AssessmentOptions) string {
+	return strings.Join([]string{
+		kind,
+		category,
+		strings.TrimSpace(person1.Name),
+		strings.ToUpper(strings.TrimSpace(person1.MBTI)),
+		strings.TrimSpace(person2.Name),
+		strings.ToUpper(strings.TrimSpace(person2.MBTI)),
+		options.key(),
+	}, "\x00")
+}
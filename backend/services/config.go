@@ -0,0 +1,67 @@
+package services
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envDuration reads a Go duration (e.g. "30s") from the environment, returning
+// def when the variable is unset or invalid
+func envDuration(name string, def time.Duration) time.Duration {
+	raw := strings.TrimSpace(os.Getenv(name))
+	if raw == "" {
+		return def
+	}
+
+	value, err := time.ParseDuration(raw)
+	if err != nil || value < 0 {
+		log.Printf("Ignoring invalid %s=%q, using %s", name, raw, def)
+		return def
+	}
+	return value
+}
+
+// envInt reads an integer from the environment, returning def when the variable is unset or invalid
+func envInt(name string, def int) int {
+	raw := strings.TrimSpace(os.Getenv(name))
+	if raw == "" {
+		return def
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		log.Printf("Ignoring invalid %s=%q, using %d", name, raw, def)
+		return def
+	}
+	return value
+}
+
+// envFloat reads a non-negative number from the environment, returning def when the variable is unset or invalid
+func envFloat(name string, def float64) float64 {
+	raw := strings.TrimSpace(os.Getenv(name))
+	if raw == "" {
+		return def
+	}
+
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || value < 0 {
+		log.Printf("Ignoring invalid %s=%q, using %g", name, raw, def)
+		return def
+	}
+	return value
+}
+
+// envBool reads a boolean flag from the environment, returning def when the variable is unset or invalid
+func envBool(name string, def bool) bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(name))) {
+	case "true", "1", "on", "yes":
+		return true
+	case "false", "0", "off", "no":
+		return false
+	default:
+		return def
+	}
+}
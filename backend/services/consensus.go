@@ -0,0 +1,191 @@
+package services
+
+import (
+	"compatiblah/backend/models"
+	"context"
+	"sort"
+	"sync"
+)
+
+// Confidence levels derived from how far apart the model samples scored
+const (
+	ConfidenceHigh   = "high"
+	ConfidenceMedium = "medium"
+	ConfidenceLow    = "low"
+)
+
+// Consensus describes how the model samples behind a result agreed
+type Consensus struct {
+	// Samples is the number of samples that succeeded and were combined
+	Samples int `json:"samples"`
+	// Scores are the raw model scores per category, one per sample
+	Scores map[string][]int `json:"scores"`
+	// Spread is the difference between the highest and lowest score per category
+	Spread map[string]int `json:"spread"`
+	// Confidence summarizes the largest spread: high, medium or low
+	Confidence string `json:"confidence"`
+}
+
+// newConsensus summarizes the raw model scores of each category
+func newConsensus(samples int, scores map[string][]int) *Consensus {
+	consensus := &Consensus{Samples: samples, Scores: scores, Spread: make(map[string]int, len(scores))}
+
+	widest := 0
+	for category, values := range scores {
+		spread := scoreSpread(values)
+		consensus.Spread[category] = spread
+		if spread > widest {
+			widest = spread
+		}
+	}
+	consensus.Confidence = confidenceFor(widest)
+	return consensus
+}
+
+// confidenceFor maps a score spread to a confidence level
+func confidenceFor(spread int) string {
+	switch {
+	case spread == 0:
+		return ConfidenceHigh
+	case spread == 1:
+		return ConfidenceMedium
+	default:
+		return ConfidenceLow
+	}
+}
+
+func scoreSpread(values []int) int {
+	if len(values) == 0 {
+		return 0
+	}
+	lowest, highest := values[0], values[0]
+	for _, value := range values[1:] {
+		lowest = min(lowest, value)
+		highest = max(highest, value)
+	}
+	return highest - lowest
+}
+
+// medianScore returns the median of values, rounding half up between the two
+// middle values of an even count
+func medianScore(values []int) int {
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[middle]
+	}
+	return (sorted[middle-1] + sorted[middle] + 1) / 2
+}
+
+func absInt(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+// collectSamples runs sample n times concurrently and returns the successful
+// results in order. It fails only when every sample failed, with the first error.
+func collectSamples(ctx context.Context, n int, sample func(ctx context.Context) (interface{}, error)) ([]interface{}, error) {
+	values := make([]interface{}, n)
+	errs := make([]error, n)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			values[i], errs[i] = sample(ctx)
+		}(i)
+	}
+	wg.Wait()
+
+	var succeeded []interface{}
+	var firstErr error
+	for i := range values {
+		if errs[i] != nil {
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+			continue
+		}
+		succeeded = append(succeeded, values[i])
+	}
+
+	if len(succeeded) == 0 {
+		return nil, firstErr
+	}
+	if firstErr != nil {
+		incMetric("consensus_samples_failed")
+	}
+	return succeeded, nil
+}
+
+// generateAssessmentConsensus samples the full assessment n times and combines
+// the median score per category with the explanations of the sample closest to
+// those medians
+func generateAssessmentConsensus(ctx context.Context, person1, person2 models.PersonData, options AssessmentOptions) (*models.GeminiResponse, *Consensus, error) {
+	values, err := collectSamples(ctx, options.Samples, func(ctx context.Context) (interface{}, error) {
+		return generateAssessment(ctx, person1, person2, options)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scores := map[string][]int{}
+	for _, value := range values {
+		sample := value.(*models.GeminiResponse)
+		scores["friend"] = append(scores["friend"], validModelScore(sample.FriendScore))
+		scores["coworker"] = append(scores["coworker"], validModelScore(sample.CoworkerScore))
+		scores["partner"] = append(scores["partner"], validModelScore(sample.PartnerScore))
+	}
+
+	friend, coworker, partner := medianScore(scores["friend"]), medianScore(scores["coworker"]), medianScore(scores["partner"])
+
+	best, bestDistance := 0, -1
+	for i := range values {
+		distance := absInt(scores["friend"][i]-friend) + absInt(scores["coworker"][i]-coworker) + absInt(scores["partner"][i]-partner)
+		if bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = i, distance
+		}
+	}
+
+	result := *values[best].(*models.GeminiResponse)
+	result.FriendScore = friend
+	result.CoworkerScore = coworker
+	result.PartnerScore = partner
+	result.OverallScore = OverallScore(friend, coworker, partner)
+
+	return &result, newConsensus(len(values), scores), nil
+}
+
+// generateCategoryConsensus samples a category prompt n times and combines the
+// median score with the explanation of the sample closest to it
+func generateCategoryConsensus(ctx context.Context, category, prompt string, options AssessmentOptions) (*categoryPayload, *Consensus, error) {
+	values, err := collectSamples(ctx, options.Samples, func(ctx context.Context) (interface{}, error) {
+		return generateCategoryPayload(ctx, category, prompt, options)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var scores []int
+	for _, value := range values {
+		scores = append(scores, validModelScore(value.(*categoryPayload).Score))
+	}
+	median := medianScore(scores)
+
+	best := 0
+	for i, score := range scores {
+		if absInt(score-median) < absInt(scores[best]-median) {
+			best = i
+		}
+	}
+
+	payload := *values[best].(*categoryPayload)
+	payload.Score = median
+
+	return &payload, newConsensus(len(values), map[string][]int{category: scores}), nil
+}
@@ -0,0 +1,359 @@
+package services
+
+import (
+	"bytes"
+	"compatiblah/backend/models"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+)
+
+// decodeShape is what a model response is decoded into
+type decodeShape string
+
+const (
+	// shapeAssessment is a full three-category assessment
+	shapeAssessment decodeShape = "assessment"
+	// shapeCategory is a single category score and explanation
+	shapeCategory decodeShape = "category"
+)
+
+// decodeTarget describes the expected model output
+type decodeTarget struct {
+	Shape decodeShape
+	// Category is the API category name ("friend", "coworker", "partner") for shapeCategory
+	Category string
+	// Language is the language code of the fallback headings and titles that
+	// legacy formats are filled in with, empty for English
+	Language string
+	// Structured means the output was constrained to the response schema, so
+	// only strict adapters apply and anything else is an error
+	Structured bool
+}
+
+// decodeResult is decoded model output. Exactly one of Assessment and Category
+// is set, matching the target shape.
+type decodeResult struct {
+	Assessment *models.GeminiResponse
+	Category   *categoryPayload
+	// Adapter names the format adapter that matched
+	Adapter string
+}
+
+// formatAdapter decodes one output format into one shape, returning an error
+// when the text is not in that format
+type formatAdapter struct {
+	name   string
+	shape  decodeShape
+	strict bool
+	decode func(text string, target decodeTarget) (*decodeResult, error)
+}
+
+// formatAdapters are tried in order; the first that decodes the text wins.
+// Lenient adapters cover the formats earlier prompts asked for, newest first.
+var formatAdapters = []formatAdapter{
+	{name: "strict", shape: shapeAssessment, strict: true, decode: decodeStrictAssessment},
+	{name: "strict", shape: shapeCategory, strict: true, decode: decodeStrictCategory},
+	// Content sections are also valid current-format JSON (the content is
+	// ignored), so they have to be recognized first
+	{name: "sections_content", shape: shapeAssessment, decode: decodeSectionsContentAssessment},
+	{name: "json", shape: shapeAssessment, decode: decodeJSONAssessment},
+	{name: "json", shape: shapeCategory, decode: decodeJSONCategory},
+	{name: "plain_text", shape: shapeAssessment, decode: decodePlainTextAssessment},
+	{name: "plain_text", shape: shapeCategory, decode: decodePlainTextCategory},
+}
+
+// decodeModelOutput decodes raw model text into the target shape using the
+// first matching format adapter
+func decodeModelOutput(text string, target decodeTarget) (*decodeResult, error) {
+	var failures []string
+	for _, adapter := range formatAdapters {
+		if adapter.shape != target.Shape || adapter.strict != target.Structured {
+			continue
+		}
+
+		result, err := adapter.decode(text, target)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", adapter.name, err))
+			continue
+		}
+
+		result.Adapter = adapter.name
+		incMetric("decode_" + adapter.name)
+		if len(failures) > 0 {
+			log.Printf("Decoded %s output with the %s adapter after: %s", target.Shape, adapter.name, strings.Join(failures, "; "))
+		}
+		return result, nil
+	}
+
+	incMetric("decode_failed")
+	return nil, unparseable(fmt.Errorf("no %s format matched: %s", target.Shape, strings.Join(failures, "; ")))
+}
+
+func decodeStrictAssessment(text string, target decodeTarget) (*decodeResult, error) {
+	var result models.GeminiResponse
+	if err := decodeStrict(text, &result); err != nil {
+		return nil, err
+	}
+	return &decodeResult{Assessment: &result}, nil
+}
+
+func decodeStrictCategory(text string, target decodeTarget) (*decodeResult, error) {
+	var result categoryPayload
+	if err := decodeStrict(text, &result); err != nil {
+		return nil, err
+	}
+	return &decodeResult{Category: &result}, nil
+}
+
+// legacySection is an explanation section whose body is a single content string
+type legacySection struct {
+	Heading string `json:"heading"`
+	Content string `json:"content"`
+}
+
+// decodeSectionsContentAssessment reads the intermediate format whose sections
+// carry a content paragraph instead of subcategories
+func decodeSectionsContentAssessment(text string, target decodeTarget) (*decodeResult, error) {
+	type explanation struct {
+		Sections []legacySection `json:"sections"`
+	}
+	var intermediate struct {
+		FriendScore         int         `json:"friend_score"`
+		CoworkerScore       int         `json:"coworker_score"`
+		PartnerScore        int         `json:"partner_score"`
+		OverallScore        int         `json:"overall_score"`
+		FriendExplanation   explanation `json:"friend_explanation"`
+		CoworkerExplanation explanation `json:"coworker_explanation"`
+		PartnerExplanation  explanation `json:"partner_explanation"`
+	}
+	if err := json.Unmarshal([]byte(cleanJSONForParsing(extractJSON(text))), &intermediate); err != nil {
+		return nil, err
+	}
+
+	hasContent := false
+	for _, sections := range [][]legacySection{intermediate.FriendExplanation.Sections, intermediate.CoworkerExplanation.Sections, intermediate.PartnerExplanation.Sections} {
+		for _, section := range sections {
+			if strings.TrimSpace(section.Content) != "" {
+				hasContent = true
+			}
+		}
+	}
+	if !hasContent {
+		return nil, fmt.Errorf("no section content")
+	}
+
+	return &decodeResult{Assessment: &models.GeminiResponse{
+		FriendScore:         intermediate.FriendScore,
+		CoworkerScore:       intermediate.CoworkerScore,
+		PartnerScore:        intermediate.PartnerScore,
+		OverallScore:        intermediate.OverallScore,
+		FriendExplanation:   convertSectionsToSubcategories(intermediate.FriendExplanation.Sections, "friendship", target.Language),
+		CoworkerExplanation: convertSectionsToSubcategories(intermediate.CoworkerExplanation.Sections, "workplace", target.Language),
+		PartnerExplanation:  convertSectionsToSubcategories(intermediate.PartnerExplanation.Sections, "romance", target.Language),
+	}}, nil
+}
+
+// decodeJSONAssessment reads the current format from text that may wrap the
+// JSON in prose or markdown fences or contain trailing commas
+func decodeJSONAssessment(text string, target decodeTarget) (*decodeResult, error) {
+	var result models.GeminiResponse
+	if err := json.Unmarshal([]byte(extractJSON(text)), &result); err != nil {
+		return nil, err
+	}
+	return &decodeResult{Assessment: &result}, nil
+}
+
+func decodeJSONCategory(text string, target decodeTarget) (*decodeResult, error) {
+	var result categoryPayload
+	if err := json.Unmarshal([]byte(extractJSON(text)), &result); err != nil {
+		return nil, err
+	}
+	return &decodeResult{Category: &result}, nil
+}
+
+// decodePlainTextAssessment reads the oldest format, where each explanation is a single string
+func decodePlainTextAssessment(text string, target decodeTarget) (*decodeResult, error) {
+	var stringFormat struct {
+		FriendScore         int    `json:"friend_score"`
+		CoworkerScore       int    `json:"coworker_score"`
+		PartnerScore        int    `json:"partner_score"`
+		OverallScore        int    `json:"overall_score"`
+		FriendExplanation   string `json:"friend_explanation"`
+		CoworkerExplanation string `json:"coworker_explanation"`
+		PartnerExplanation  string `json:"partner_explanation"`
+	}
+	if err := json.Unmarshal([]byte(cleanJSONForParsing(extractJSON(text))), &stringFormat); err != nil {
+		return nil, err
+	}
+
+	return &decodeResult{Assessment: &models.GeminiResponse{
+		FriendScore:         stringFormat.FriendScore,
+		CoworkerScore:       stringFormat.CoworkerScore,
+		PartnerScore:        stringFormat.PartnerScore,
+		OverallScore:        stringFormat.OverallScore,
+		FriendExplanation:   convertStringToStructured(stringFormat.FriendExplanation, "friendship", target.Language),
+		CoworkerExplanation: convertStringToStructured(stringFormat.CoworkerExplanation, "workplace", target.Language),
+		PartnerExplanation:  convertStringToStructured(stringFormat.PartnerExplanation, "romance", target.Language),
+	}}, nil
+}
+
+func decodePlainTextCategory(text string, target decodeTarget) (*decodeResult, error) {
+	var stringFormat struct {
+		Score       int    `json:"score"`
+		Explanation string `json:"explanation"`
+	}
+	if err := json.Unmarshal([]byte(cleanJSONForParsing(extractJSON(text))), &stringFormat); err != nil {
+		return nil, err
+	}
+
+	return &decodeResult{Category: &categoryPayload{
+		Score:       stringFormat.Score,
+		Explanation: convertStringToStructured(stringFormat.Explanation, explanationCategory(target.Category), target.Language),
+	}}, nil
+}
+
+// decodeStrict decodes a schema-constrained response, rejecting unknown fields and trailing data
+func decodeStrict(text string, target interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(text)))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(target); err != nil {
+		return err
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		return fmt.Errorf("unexpected data after JSON object")
+	}
+
+	return nil
+}
+
+// extractJSON returns the first complete JSON object in text with trailing commas removed
+func extractJSON(text string) string {
+	// Try to find JSON in the text, ignoring braces inside strings
+	startIdx := strings.IndexByte(text, '{')
+	endIdx := -1
+	if startIdx != -1 {
+		if end := matchingBrace(text[startIdx:]); end != -1 {
+			endIdx = startIdx + end + 1
+		}
+	}
+
+	var jsonText string
+	if startIdx != -1 && endIdx != -1 {
+		jsonText = text[startIdx:endIdx]
+	} else {
+		// If no JSON found, return the whole text (might be just JSON)
+		jsonText = text
+	}
+
+	// Fix common JSON issues: trailing commas before closing braces/brackets
+	// Use regex-like approach to remove trailing commas more comprehensively
+
+	// Remove trailing comma before } (handle various whitespace patterns)
+	jsonText = strings.ReplaceAll(jsonText, ",}", "}")
+	jsonText = strings.ReplaceAll(jsonText, ", }", " }")
+	jsonText = strings.ReplaceAll(jsonText, ",\n}", "\n}")
+	jsonText = strings.ReplaceAll(jsonText, ",\r\n}", "\r\n}")
+	jsonText = strings.ReplaceAll(jsonText, ",\r}", "\r}")
+	// Handle cases with spaces before comma
+	jsonText = strings.ReplaceAll(jsonText, " ,}", "}")
+	jsonText = strings.ReplaceAll(jsonText, " , }", " }")
+
+	// Remove trailing comma before ] (handle various whitespace patterns)
+	jsonText = strings.ReplaceAll(jsonText, ",]", "]")
+	jsonText = strings.ReplaceAll(jsonText, ", ]", " ]")
+	jsonText = strings.ReplaceAll(jsonText, ",\n]", "\n]")
+	jsonText = strings.ReplaceAll(jsonText, ",\r\n]", "\r\n]")
+	jsonText = strings.ReplaceAll(jsonText, ",\r]", "\r]")
+	// Handle cases with spaces before comma
+	jsonText = strings.ReplaceAll(jsonText, " ,]", "]")
+	jsonText = strings.ReplaceAll(jsonText, " , ]", " ]")
+
+	// More aggressive: remove trailing comma after last quote before closing brace
+	// This handles cases like: "value",\n}
+	jsonBytes := []byte(jsonText)
+	result := []byte{}
+	inString := false
+	escapeNext := false
+
+	for i := 0; i < len(jsonBytes); i++ {
+		char := jsonBytes[i]
+
+		if escapeNext {
+			result = append(result, char)
+			escapeNext = false
+			continue
+		}
+
+		if char == '\\' {
+			escapeNext = true
+			result = append(result, char)
+			continue
+		}
+
+		if char == '"' {
+			inString = !inString
+			result = append(result, char)
+			continue
+		}
+
+		// If we're outside a string and find ",}" or ",\n}" or similar, skip the comma
+		if !inString && char == ',' {
+			// Look ahead to see if next non-whitespace is } or ]
+			j := i + 1
+			for j < len(jsonBytes) {
+				nextChar := jsonBytes[j]
+				if nextChar == ' ' || nextChar == '\n' || nextChar == '\r' || nextChar == '\t' {
+					j++
+					continue
+				}
+				if nextChar == '}' || nextChar == ']' {
+					// Skip this comma, don't append it
+					i = j - 1 // Will be incremented by loop
+					break
+				}
+				// Not a closing brace/bracket, keep the comma
+				result = append(result, char)
+				break
+			}
+			if j >= len(jsonBytes) {
+				result = append(result, char)
+			}
+		} else {
+			result = append(result, char)
+		}
+	}
+
+	return string(result)
+}
+
+// cleanJSONForParsing applies additional cleaning passes to ensure valid JSON
+func cleanJSONForParsing(jsonText string) string {
+	// Apply multiple cleaning passes
+	cleaned := jsonText
+
+	// Remove trailing commas more aggressively
+	// Pattern: "value",\n} -> "value"\n}
+	cleaned = strings.ReplaceAll(cleaned, "\",\n}", "\"\n}")
+	cleaned = strings.ReplaceAll(cleaned, "\",\r\n}", "\"\r\n}")
+	cleaned = strings.ReplaceAll(cleaned, "\", }", "\" }")
+	cleaned = strings.ReplaceAll(cleaned, "\",}", "\"}")
+
+	// Remove trailing commas after numbers
+	cleaned = strings.ReplaceAll(cleaned, ",\n}", "\n}")
+	cleaned = strings.ReplaceAll(cleaned, ",\r\n}", "\r\n}")
+	cleaned = strings.ReplaceAll(cleaned, ", }", " }")
+	cleaned = strings.ReplaceAll(cleaned, ",}", "}")
+
+	// Remove trailing commas before closing bracket
+	cleaned = strings.ReplaceAll(cleaned, ",\n]", "\n]")
+	cleaned = strings.ReplaceAll(cleaned, ",\r\n]", "\r\n]")
+	cleaned = strings.ReplaceAll(cleaned, ", ]", " ]")
+	cleaned = strings.ReplaceAll(cleaned, ",]", "]")
+
+	return cleaned
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"bytes"
+	"compatiblah/backend/models"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the decoder golden files")
+
+// decoderCorpus maps each directory under testdata/decoder to the target its inputs are decoded into
+var decoderCorpus = map[string]decodeTarget{
+	"assessment":            {Shape: shapeAssessment},
+	"category":              {Shape: shapeCategory, Category: "friend"},
+	"structured-assessment": {Shape: shapeAssessment, Structured: true},
+	"structured-category":   {Shape: shapeCategory, Category: "partner", Structured: true},
+}
+
+// decoderGolden is the recorded outcome of decoding one corpus input
+type decoderGolden struct {
+	Adapter    string                 `json:"adapter,omitempty"`
+	Failed     bool                   `json:"failed,omitempty"`
+	Assessment *models.GeminiResponse `json:"assessment,omitempty"`
+	Category   *categoryPayload       `json:"category,omitempty"`
+}
+
+// TestDecodeModelOutputGolden decodes every input in testdata/decoder and compares
+// the outcome with its .golden.json file. Run with -update to rewrite them.
+func TestDecodeModelOutputGolden(t *testing.T) {
+	dirs := make([]string, 0, len(decoderCorpus))
+	for dir := range decoderCorpus {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		target := decoderCorpus[dir]
+		inputs, err := filepath.Glob(filepath.Join("testdata", "decoder", dir, "*.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(inputs) == 0 {
+			t.Fatalf("no corpus inputs in testdata/decoder/%s", dir)
+		}
+
+		for _, input := range inputs {
+			input := input
+			name := dir + "/" + strings.TrimSuffix(filepath.Base(input), ".txt")
+			t.Run(name, func(t *testing.T) {
+				raw, err := os.ReadFile(input)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var got decoderGolden
+				result, err := decodeModelOutput(string(raw), target)
+				if err != nil {
+					if kind := KindOf(err); kind != ErrUnparseable {
+						t.Fatalf("error kind = %s, want %s: %v", kind, ErrUnparseable, err)
+					}
+					got.Failed = true
+				} else {
+					got.Adapter = result.Adapter
+					got.Assessment = result.Assessment
+					got.Category = result.Category
+				}
+
+				gotJSON, err := json.MarshalIndent(got, "", "  ")
+				if err != nil {
+					t.Fatal(err)
+				}
+				gotJSON = append(gotJSON, '\n')
+
+				goldenPath := strings.TrimSuffix(input, ".txt") + ".golden.json"
+				if *updateGolden {
+					if err := os.WriteFile(goldenPath, gotJSON, 0o644); err != nil {
+						t.Fatal(err)
+					}
+					return
+				}
+
+				want, err := os.ReadFile(goldenPath)
+				if err != nil {
+					t.Fatalf("missing golden file (run go test -run TestDecodeModelOutputGolden -update): %v", err)
+				}
+				if !bytes.Equal(gotJSON, want) {
+					t.Errorf("decoded output differs from %s\ngot:\n%s\nwant:\n%s", goldenPath, gotJSON, want)
+				}
+			})
+		}
+	}
+}
+
+func TestDecodeModelOutputOnlyTriesAdaptersForShape(t *testing.T) {
+	category := `{"score": 4, "explanation": {"sections": []}}`
+
+	if _, err := decodeModelOutput(category, decodeTarget{Shape: shapeAssessment, Structured: true}); err == nil {
+		t.Fatal("category JSON decoded as a strict assessment")
+	}
+
+	result, err := decodeModelOutput(category, decodeTarget{Shape: shapeCategory, Category: "coworker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Assessment != nil || result.Category == nil || result.Category.Score != 4 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+}
+
+func TestExtractJSONIgnoresBracesInStrings(t *testing.T) {
+	got := extractJSON(`Answer: {"text": "a } b { c", "n": 1,} trailing {"x": 2}`)
+	want := `{"text": "a } b { c", "n": 1}`
+	if got != want {
+		t.Fatalf("extractJSON = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"compatiblah/backend/models"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrorKind classifies assessment failures. Its value doubles as the stable
+// machine-readable error code returned to API clients.
+type ErrorKind string
+
+const (
+	// ErrRateLimited means the LLM provider throttled us and retries ran out
+	ErrRateLimited ErrorKind = "rate_limited"
+	// ErrUpstreamUnavailable means the LLM provider could not be reached or kept failing
+	ErrUpstreamUnavailable ErrorKind = "upstream_unavailable"
+	// ErrUpstreamRejected means the provider refused the request, e.g. a bad API key
+	ErrUpstreamRejected ErrorKind = "upstream_error"
+	// ErrSafetyBlocked means the provider declined to answer for safety reasons
+	ErrSafetyBlocked ErrorKind = "safety_blocked"
+	// ErrUnparseable means the model answered but the output could not be decoded
+	ErrUnparseable ErrorKind = "unparseable_output"
+	// ErrInvalidInput means the request cannot be assessed as given
+	ErrInvalidInput ErrorKind = "invalid_input"
+	// ErrInternal covers everything else
+	ErrInternal ErrorKind = "internal_error"
+)
+
+// Error is a classified assessment failure. Message is safe to show to clients;
+// Err keeps the underlying detail, which may include upstream response bodies,
+// for logging only.
+type Error struct {
+	Kind    ErrorKind
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func newError(kind ErrorKind, message string, err error) *Error {
+	return &Error{Kind: kind, Message: message, Err: err}
+}
+
+// KindOf returns the kind of a classified error, or ErrInternal
+func KindOf(err error) ErrorKind {
+	var classified *Error
+	if errors.As(err, &classified) {
+		return classified.Kind
+	}
+	if providerUnavailable(err) {
+		return ErrUpstreamUnavailable
+	}
+	return ErrInternal
+}
+
+// PublicMessage returns a client-safe description of err
+func PublicMessage(err error) string {
+	var classified *Error
+	if errors.As(err, &classified) {
+		return classified.Message
+	}
+	if providerUnavailable(err) {
+		return "The assessment service is temporarily unavailable"
+	}
+	return "Something went wrong while assessing compatibility"
+}
+
+// unparseable wraps a decoding failure of model output
+func unparseable(err error) error {
+	return newError(ErrUnparseable, "The model returned a response that could not be understood", err)
+}
+
+// upstreamUnreachable wraps a network failure talking to the provider
+func upstreamUnreachable(err error) error {
+	return newError(ErrUpstreamUnavailable, "The assessment service is temporarily unavailable", err)
+}
+
+// upstreamStatusError classifies a non-200 response from the provider
+func upstreamStatusError(statusCode int, body []byte) error {
+	err := fmt.Errorf("API error: status %d, body: %s", statusCode, string(body))
+
+	switch {
+	case statusCode == 429:
+		return newError(ErrRateLimited, "The assessment service is busy, please try again shortly", err)
+	case statusCode >= 500:
+		return newError(ErrUpstreamUnavailable, "The assessment service is temporarily unavailable", err)
+	default:
+		return newError(ErrUpstreamRejected, "The assessment service rejected the request", err)
+	}
+}
+
+// safetyBlocked reports that the provider refused to generate for safety
+// reasons. The reason and ratings are kept for logs only.
+func safetyBlocked(reason string, ratings []SafetyRating) error {
+	incMetric("safety_blocked")
+
+	var flagged []string
+	for _, rating := range ratings {
+		if rating.Blocked || rating.Probability == "HIGH" || rating.Probability == "MEDIUM" {
+			flagged = append(flagged, rating.Category+"="+rating.Probability)
+		}
+	}
+
+	err := fmt.Errorf("blocked by provider: reason %s", reason)
+	if len(flagged) > 0 {
+		err = fmt.Errorf("blocked by provider: reason %s, ratings %s", reason, strings.Join(flagged, ", "))
+	}
+	return newError(ErrSafetyBlocked, "This request was blocked by the content safety filter. Please check the names and try again", err)
+}
+
+// validateInput checks the people and, unless empty, the category of an assessment request
+func validateInput(person1, person2 models.PersonData, category string) error {
+	for i, person := range []models.PersonData{person1, person2} {
+		if strings.TrimSpace(person.Name) == "" {
+			return newError(ErrInvalidInput, fmt.Sprintf("Person %d must have a name", i+1), nil)
+		}
+		if _, ok := parseMBTIProfile(person.MBTI); !ok {
+			return newError(ErrInvalidInput, fmt.Sprintf("Person %d has an invalid MBTI type %q", i+1, person.MBTI), nil)
+		}
+	}
+
+	switch category {
+	case "", "friend", "coworker", "partner":
+		return nil
+	default:
+		return newError(ErrInvalidInput, "Category must be 'friend', 'coworker', or 'partner'", nil)
+	}
+}
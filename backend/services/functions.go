@@ -0,0 +1,110 @@
+package services
+
+import "compatiblah/backend/models"
+
+// cognitiveFunction is one of the eight Jungian functions, e.g. Ni or Te
+type cognitiveFunction struct {
+	kind     rune // N, S, T or F
+	attitude rune // e (extraverted) or i (introverted)
+}
+
+func (f cognitiveFunction) perceiving() bool {
+	return f.kind == 'N' || f.kind == 'S'
+}
+
+// functionStack is a type's dominant, auxiliary, tertiary and inferior functions
+type functionStack [4]cognitiveFunction
+
+// functionWeights are the per-category adjustments of the functions scoring model
+type functionWeights struct {
+	SameDominant   float64 `json:"same_dominant"`   // both lead with the same function
+	Mirrored       float64 `json:"mirrored"`        // each one's dominant is the other's auxiliary
+	Supports       float64 `json:"supports"`        // one's dominant is the other's auxiliary
+	BlindSpot      float64 `json:"blind_spot"`      // one's dominant is the other's inferior
+	SamePerceiving float64 `json:"same_perceiving"` // same perceiving function in the top two
+	PerceivingGap  float64 `json:"perceiving_gap"`  // intuition meets sensing in the top two
+	SameJudging    float64 `json:"same_judging"`    // same judging function in the top two
+	JudgingGap     float64 `json:"judging_gap"`     // thinking meets feeling in the top two
+}
+
+// cognitiveStack derives the function stack of a type, e.g. INTJ is Ni-Te-Fi-Se
+func cognitiveStack(profile mbtiProfile) functionStack {
+	perceiving, judging := profile.intuition, profile.decision
+
+	// J/P names the function a type extraverts
+	extraverted, introverted := judging, perceiving
+	if profile.lifestyle == 'P' {
+		extraverted, introverted = perceiving, judging
+	}
+
+	dominant := cognitiveFunction{kind: introverted, attitude: 'i'}
+	auxiliary := cognitiveFunction{kind: extraverted, attitude: 'e'}
+	if profile.energy == 'E' {
+		dominant = cognitiveFunction{kind: extraverted, attitude: 'e'}
+		auxiliary = cognitiveFunction{kind: introverted, attitude: 'i'}
+	}
+
+	tertiary := cognitiveFunction{kind: opposingFunction(auxiliary.kind), attitude: dominant.attitude}
+	inferior := cognitiveFunction{kind: opposingFunction(dominant.kind), attitude: auxiliary.attitude}
+	return functionStack{dominant, auxiliary, tertiary, inferior}
+}
+
+func opposingFunction(kind rune) rune {
+	switch kind {
+	case 'N':
+		return 'S'
+	case 'S':
+		return 'N'
+	case 'T':
+		return 'F'
+	default:
+		return 'T'
+	}
+}
+
+// leading returns the perceiving and judging functions among the dominant and auxiliary
+func (s functionStack) leading() (perceiving, judging cognitiveFunction) {
+	if s[0].perceiving() {
+		return s[0], s[1]
+	}
+	return s[1], s[0]
+}
+
+// functionContributions scores how the dominant and auxiliary functions of two
+// types line up; every term is symmetric so the order of the people does not matter
+func functionContributions(weights functionWeights, profile1, profile2 mbtiProfile) []models.ScoreContribution {
+	stack1, stack2 := cognitiveStack(profile1), cognitiveStack(profile2)
+
+	var dominant float64
+	switch {
+	case stack1[0] == stack2[0]:
+		dominant = weights.SameDominant
+	case stack1[0] == stack2[1] && stack2[0] == stack1[1]:
+		dominant = weights.Mirrored
+	case stack1[0] == stack2[1] || stack2[0] == stack1[1]:
+		dominant = weights.Supports
+	case stack1[0] == stack2[3] || stack2[0] == stack1[3]:
+		dominant = weights.BlindSpot
+	}
+
+	perceiving1, judging1 := stack1.leading()
+	perceiving2, judging2 := stack2.leading()
+	return []models.ScoreContribution{
+		{Dimension: "dominant", Value: dominant},
+		{Dimension: "perceiving", Value: functionMatch(perceiving1, perceiving2, weights.SamePerceiving, weights.PerceivingGap)},
+		{Dimension: "judging", Value: functionMatch(judging1, judging2, weights.SameJudging, weights.JudgingGap)},
+	}
+}
+
+// functionMatch rewards the same function, half as much when its attitude
+// differs (e.g. Ni and Ne), and applies gap when the functions oppose
+func functionMatch(a, b cognitiveFunction, same, gap float64) float64 {
+	switch {
+	case a == b:
+		return same
+	case a.kind == b.kind:
+		return same / 2
+	default:
+		return gap
+	}
+}
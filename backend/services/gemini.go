@@ -1,121 +1,77 @@
 package services
 
 import (
-	"bytes"
 	"compatiblah/backend/models"
-	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
-	"os"
+	"context"
 	"strings"
-	"time"
 )
 
-func AssessCompatibility(person1, person2 models.PersonData) (*models.GeminiResponse, error) {
-	prompt := buildPrompt(person1, person2)
+// AssessmentResult is a full three-category assessment and where it came from
+type AssessmentResult struct {
+	models.GeminiResponse
+	// Source is the LLM provider name, or "heuristic" when the provider was unavailable
+	Source string `json:"source"`
+	// PromptVersion identifies the prompt template used, empty for heuristic results
+	PromptVersion string `json:"prompt_version,omitempty"`
+	// WeightsVersion identifies the scoring weights used
+	WeightsVersion string `json:"weights_version"`
+	// ScoreDetails explains each category score
+	ScoreDetails models.ScoreBreakdown `json:"score_details"`
+	// PreciseScores are the continuous 0-100 scores behind the 1-5 scores
+	PreciseScores models.PreciseScores `json:"precise_scores"`
+	// Consensus is set when several model samples were combined
+	Consensus *Consensus `json:"consensus,omitempty"`
+}
 
-	body, err := callGeminiAPI(prompt)
-	if err != nil {
+func AssessCompatibility(ctx context.Context, person1, person2 models.PersonData, options AssessmentOptions) (*AssessmentResult, error) {
+	if err := validateInput(person1, person2, ""); err != nil {
 		return nil, err
 	}
-
-	// Parse Gemini response
-	var geminiResp struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text string `json:"text"`
-				} `json:"parts"`
-			} `json:"content"`
-		} `json:"candidates"`
-	}
-
-	if err := json.Unmarshal(body, &geminiResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+	if err := options.validate(); err != nil {
+		return nil, err
 	}
+	options = options.withDefaults()
 
-	if len(geminiResp.Candidates) == 0 || len(geminiResp.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("no content in response")
+	// Identical concurrent requests share a single upstream call
+	key := coalesceKey("full", "all", person1, person2, options)
+	value, err, _ := assessmentCoalescer.do(ctx, key, func(ctx context.Context) (interface{}, error) {
+		return assessCompatibility(ctx, person1, person2, options)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	text := geminiResp.Candidates[0].Content.Parts[0].Text
+	result := *value.(*AssessmentResult)
+	return &result, nil
+}
 
-	// Extract JSON from the response text (Gemini might wrap it in markdown)
-	jsonText := extractJSON(text)
+func assessCompatibility(ctx context.Context, person1, person2 models.PersonData, options AssessmentOptions) (*AssessmentResult, error) {
+	cache := newResponseCache(person1, person2, "all", options)
 
-	// Try to parse as new structured format first
 	var result models.GeminiResponse
-	err = json.Unmarshal([]byte(jsonText), &result)
-
-	// If parsing fails, try old format (backward compatibility)
-	if err != nil {
-		// Try intermediate format with sections but content field (intermediate format)
-		var intermediateFormat struct {
-			FriendScore       int `json:"friend_score"`
-			CoworkerScore     int `json:"coworker_score"`
-			PartnerScore      int `json:"partner_score"`
-			OverallScore      int `json:"overall_score"`
-			FriendExplanation struct {
-				Sections []struct {
-					Heading string `json:"heading"`
-					Content string `json:"content"`
-				} `json:"sections"`
-			} `json:"friend_explanation"`
-			CoworkerExplanation struct {
-				Sections []struct {
-					Heading string `json:"heading"`
-					Content string `json:"content"`
-				} `json:"sections"`
-			} `json:"coworker_explanation"`
-			PartnerExplanation struct {
-				Sections []struct {
-					Heading string `json:"heading"`
-					Content string `json:"content"`
-				} `json:"sections"`
-			} `json:"partner_explanation"`
+	var consensus *Consensus
+	if options.Samples > 1 {
+		// Consensus results always sample fresh and are not cached as a single sample
+		generated, agreement, err := generateAssessmentConsensus(ctx, person1, person2, options)
+		if providerUnavailable(err) {
+			return heuristicAssessment(person1, person2, options.Language), nil
 		}
-
-		// Clean up JSON one more time before trying old format
-		jsonText = cleanJSONForParsing(jsonText)
-
-		if oldErr := json.Unmarshal([]byte(jsonText), &intermediateFormat); oldErr == nil {
-			// Convert intermediate format (sections with content) to new format
-			result = models.GeminiResponse{
-				FriendScore:         intermediateFormat.FriendScore,
-				CoworkerScore:       intermediateFormat.CoworkerScore,
-				PartnerScore:        intermediateFormat.PartnerScore,
-				OverallScore:        intermediateFormat.OverallScore,
-				FriendExplanation:   convertSectionsToSubcategories(intermediateFormat.FriendExplanation.Sections, "friendship"),
-				CoworkerExplanation: convertSectionsToSubcategories(intermediateFormat.CoworkerExplanation.Sections, "workplace"),
-				PartnerExplanation:  convertSectionsToSubcategories(intermediateFormat.PartnerExplanation.Sections, "romance"),
-			}
-		} else {
-			// Try oldest format (just strings)
-			var stringFormat struct {
-				FriendScore         int    `json:"friend_score"`
-				CoworkerScore       int    `json:"coworker_score"`
-				PartnerScore        int    `json:"partner_score"`
-				OverallScore        int    `json:"overall_score"`
-				FriendExplanation   string `json:"friend_explanation"`
-				CoworkerExplanation string `json:"coworker_explanation"`
-				PartnerExplanation  string `json:"partner_explanation"`
-			}
-
-			if stringErr := json.Unmarshal([]byte(jsonText), &stringFormat); stringErr == nil {
-				result = models.GeminiResponse{
-					FriendScore:         stringFormat.FriendScore,
-					CoworkerScore:       stringFormat.CoworkerScore,
-					PartnerScore:        stringFormat.PartnerScore,
-					OverallScore:        stringFormat.OverallScore,
-					FriendExplanation:   convertStringToStructured(stringFormat.FriendExplanation, "friendship"),
-					CoworkerExplanation: convertStringToStructured(stringFormat.CoworkerExplanation, "workplace"),
-					PartnerExplanation:  convertStringToStructured(stringFormat.PartnerExplanation, "romance"),
-				}
-			} else {
-				return nil, fmt.Errorf("failed to parse assessment JSON (all formats): new format error: %w, intermediate format error: %v, string format error: %v, cleaned text: %s", err, oldErr, stringErr, jsonText)
-			}
+		if err != nil {
+			return nil, err
 		}
+		result, consensus = *generated, agreement
+	} else if cached, ok := cache.loadAssessment(); ok {
+		result = *cached
+	} else {
+		generated, err := generateAssessment(ctx, person1, person2, options)
+		if providerUnavailable(err) {
+			return heuristicAssessment(person1, person2, options.Language), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		cache.storeAssessment(generated)
+		result = *generated
 	}
 
 	// Validate scores
@@ -135,901 +91,249 @@ func AssessCompatibility(person1, person2 models.PersonData) (*models.GeminiResp
 		}
 	}
 
-	heuristicScores := calculateCompatibilityScores(person1, person2)
-	result.FriendScore = blendScores(result.FriendScore, heuristicScores.Friend)
-	result.CoworkerScore = blendScores(result.CoworkerScore, heuristicScores.Coworker)
-	result.PartnerScore = blendScores(result.PartnerScore, heuristicScores.Partner)
-	result.OverallScore = clampScore(float64(result.FriendScore+result.CoworkerScore+result.PartnerScore) / 3.0)
-
-	return &result, nil
-}
+	weights := currentWeights()
+	_, breakdown := calculateCompatibilityScores(weights, person1, person2)
+	result.FriendScore, breakdown["friend"] = blendDetails(weights, result.FriendScore, breakdown["friend"])
+	result.CoworkerScore, breakdown["coworker"] = blendDetails(weights, result.CoworkerScore, breakdown["coworker"])
+	result.PartnerScore, breakdown["partner"] = blendDetails(weights, result.PartnerScore, breakdown["partner"])
+	result.OverallScore = OverallScore(result.FriendScore, result.CoworkerScore, result.PartnerScore)
 
-// CategoryResponse represents a single category assessment result
-type CategoryResponse struct {
-	Score       int                        `json:"score"`
-	Explanation models.CategoryExplanation `json:"explanation"`
+	return &AssessmentResult{
+		GeminiResponse: result,
+		Source:         llmSource(),
+		PromptVersion:  promptVersion(promptAssessment),
+		WeightsVersion: weights.Version,
+		ScoreDetails:   breakdown,
+		PreciseScores:  preciseScores(breakdown),
+		Consensus:      consensus,
+	}, nil
 }
 
-// AssessCategoryCompatibility generates compatibility assessment for a single category
-func AssessCategoryCompatibility(person1, person2 models.PersonData, category string) (*CategoryResponse, error) {
-	prompt := buildCategoryPrompt(person1, person2, category)
-
-	body, err := callGeminiAPI(prompt)
+// generateAssessment asks the model for all three categories and decodes its raw (unblended) output
+func generateAssessment(ctx context.Context, person1, person2 models.PersonData, options AssessmentOptions) (*models.GeminiResponse, error) {
+	prompt, err := buildPrompt(person1, person2, options)
 	if err != nil {
 		return nil, err
 	}
 
-	// Parse Gemini response
-	var geminiResp struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text string `json:"text"`
-				} `json:"parts"`
-			} `json:"content"`
-		} `json:"candidates"`
-	}
-
-	if err := json.Unmarshal(body, &geminiResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	if len(geminiResp.Candidates) == 0 || len(geminiResp.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("no content in response")
-	}
-
-	text := geminiResp.Candidates[0].Content.Parts[0].Text
-
-	// Extract JSON from the response text
-	jsonText := extractJSON(text)
-	jsonText = cleanJSONForParsing(jsonText)
-
-	// Try to parse as structured format
-	var result struct {
-		Score       int                        `json:"score"`
-		Explanation models.CategoryExplanation `json:"explanation"`
+	generated, err := generate(ctx, GenerationRequest{
+		Prompt:          prompt,
+		Schema:          structuredSchema(assessmentSchema),
+		Category:        "all",
+		MaxOutputTokens: options.maxOutputTokens(3),
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	err = json.Unmarshal([]byte(jsonText), &result)
-
-	// If parsing fails, try old format
+	decoded, err := decodeModelOutput(generated.Text, decodeTarget{Shape: shapeAssessment, Language: options.Language, Structured: generated.Structured})
 	if err != nil {
-		var oldFormat struct {
-			Score       int    `json:"score"`
-			Explanation string `json:"explanation"`
-		}
-
-		if oldErr := json.Unmarshal([]byte(jsonText), &oldFormat); oldErr == nil {
-			result = struct {
-				Score       int                        `json:"score"`
-				Explanation models.CategoryExplanation `json:"explanation"`
-			}{
-				Score:       oldFormat.Score,
-				Explanation: convertStringToStructured(oldFormat.Explanation, category),
-			}
-		} else {
-			return nil, fmt.Errorf("failed to parse category assessment JSON: %w", err)
-		}
+		return nil, err
 	}
-
-	// Validate score
-	if result.Score < 1 || result.Score > 5 {
-		result.Score = 3
+	if err := checkAssessmentOutput(decoded.Assessment); err != nil {
+		return nil, err
 	}
+	return decoded.Assessment, nil
+}
 
-	heuristic := calculateCategoryScore(person1, person2, category)
-	finalScore := blendScores(result.Score, heuristic)
-
-	return &CategoryResponse{
-		Score:       finalScore,
-		Explanation: result.Explanation,
-	}, nil
+// CategoryResponse represents a single category assessment result
+type CategoryResponse struct {
+	Score       int                        `json:"score"`
+	Explanation models.CategoryExplanation `json:"explanation"`
+	// Source is the LLM provider name, or "heuristic" when the provider was unavailable
+	Source string `json:"source"`
+	// PromptVersion identifies the prompt template used, empty for heuristic results
+	PromptVersion string `json:"prompt_version,omitempty"`
+	// WeightsVersion identifies the scoring weights used
+	WeightsVersion string `json:"weights_version"`
+	// ScoreDetails explains the score
+	ScoreDetails models.ScoreDetails `json:"score_details"`
+	// PreciseScore is the continuous 0-100 score behind Score
+	PreciseScore models.PreciseScore `json:"precise_score"`
+	// Consensus is set when several model samples were combined
+	Consensus *Consensus `json:"consensus,omitempty"`
 }
 
-// AssessCategoryCompatibilityWithBase assesses compatibility with optional base explanation for augmentation
-func AssessCategoryCompatibilityWithBase(person1, person2 models.PersonData, category string, baseExplanation *models.CategoryExplanation) (*CategoryResponse, error) {
-	prompt := buildCategoryPromptWithBase(person1, person2, category, baseExplanation)
+// categoryPayload is the JSON shape the model returns for a single category
+type categoryPayload struct {
+	Score       int                        `json:"score"`
+	Explanation models.CategoryExplanation `json:"explanation"`
+}
 
-	body, err := callGeminiAPI(prompt)
-	if err != nil {
+// AssessCategoryCompatibility generates compatibility assessment for a single category
+func AssessCategoryCompatibility(ctx context.Context, person1, person2 models.PersonData, category string, options AssessmentOptions) (*CategoryResponse, error) {
+	if err := validateInput(person1, person2, category); err != nil {
 		return nil, err
 	}
-
-	// Parse Gemini response
-	var geminiResp struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text string `json:"text"`
-				} `json:"parts"`
-			} `json:"content"`
-		} `json:"candidates"`
-	}
-
-	if err := json.Unmarshal(body, &geminiResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+	if err := options.validate(); err != nil {
+		return nil, err
 	}
+	options = options.withDefaults()
 
-	if len(geminiResp.Candidates) == 0 || len(geminiResp.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("no content in response")
+	// Identical concurrent requests share a single upstream call
+	key := coalesceKey("category", category, person1, person2, options)
+	value, err, _ := assessmentCoalescer.do(ctx, key, func(ctx context.Context) (interface{}, error) {
+		return assessCategoryCompatibility(ctx, person1, person2, category, options)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	text := geminiResp.Candidates[0].Content.Parts[0].Text
-
-	// Extract JSON from the response text
-	jsonText := extractJSON(text)
-	jsonText = cleanJSONForParsing(jsonText)
+	response := *value.(*CategoryResponse)
+	return &response, nil
+}
 
-	// Try to parse as structured format
-	var result struct {
-		Score       int                        `json:"score"`
-		Explanation models.CategoryExplanation `json:"explanation"`
+func assessCategoryCompatibility(ctx context.Context, person1, person2 models.PersonData, category string, options AssessmentOptions) (*CategoryResponse, error) {
+	weights := currentWeights()
+	prompt, err := buildCategoryPrompt(person1, person2, category, options)
+	if err != nil {
+		return nil, err
 	}
 
-	err = json.Unmarshal([]byte(jsonText), &result)
-
-	// If parsing fails, try old format
-	if err != nil {
-		var oldFormat struct {
-			Score       int    `json:"score"`
-			Explanation string `json:"explanation"`
+	if options.Samples > 1 {
+		// Consensus results always sample fresh and are not cached as a single sample
+		payload, consensus, err := generateCategoryConsensus(ctx, category, prompt, options)
+		if providerUnavailable(err) {
+			return heuristicCategoryResponse(weights, person1, person2, category, options.Language), nil
 		}
-
-		if oldErr := json.Unmarshal([]byte(jsonText), &oldFormat); oldErr == nil {
-			result = struct {
-				Score       int                        `json:"score"`
-				Explanation models.CategoryExplanation `json:"explanation"`
-			}{
-				Score:       oldFormat.Score,
-				Explanation: convertStringToStructured(oldFormat.Explanation, category),
-			}
-		} else {
-			return nil, fmt.Errorf("failed to parse category assessment JSON: %w", err)
+		if err != nil {
+			return nil, err
 		}
+		response := categoryResponseFromPayload(weights, person1, person2, category, payload)
+		response.Consensus = consensus
+		return response, nil
 	}
 
-	// Validate score
-	if result.Score < 1 || result.Score > 5 {
-		result.Score = 3
+	cache := newResponseCache(person1, person2, category, options)
+	if cached, ok := cache.loadCategory(); ok {
+		return categoryResponseFromPayload(weights, person1, person2, category, cached), nil
 	}
 
-	heuristic := calculateCategoryScore(person1, person2, category)
-	finalScore := blendScores(result.Score, heuristic)
-
-	return &CategoryResponse{
-		Score:       finalScore,
-		Explanation: result.Explanation,
-	}, nil
-}
-
-func buildPrompt(person1, person2 models.PersonData) string {
-	prompt := fmt.Sprintf(`You are a compatibility assessment expert. Analyze the compatibility between two people based on ALL the information provided below. You MUST consider and reference their names and MBTI types when making your assessment.
-
-PERSON 1:
-- Name: %s
-- MBTI Type: %s`, person1.Name, person1.MBTI)
-	prompt += fmt.Sprintf(`
-
-PERSON 2:
-- Name: %s
-- MBTI Type: %s`, person2.Name, person2.MBTI)
-
-	prompt += `
-
-You are an expert compatibility analyst with deep knowledge of personality psychology, relationship dynamics, and interpersonal communication. Drawing from frameworks like MBTI, cognitive functions, and relationship psychology, provide a comprehensive, insightful assessment based on the MBTI information above.
-
-CRITICAL INSTRUCTIONS:
-- You MUST reference and incorporate MBTI types and names in your analysis
-- Focus entirely on the provided MBTI information to deliver the most accurate assessment
-
-For each compatibility context (friendship, workplace, romance), provide a structured analysis with AT LEAST 3 distinct sections. Each section should have:
-- A clear heading
-- 2-3 sub-categories with descriptive titles
-- Each sub-category should contain 2-3 bullet points (detailed, not just one word)
-
-Use a mix of consistent labels (like "Strengths", "Challenges") and context-specific labels (like "What Draws Them Together" for romance, "Communication Styles" for friendship, "Collaboration Tips" for workplace) where it makes sense.
-
-Return your response as a JSON object with the following EXACT structure (no markdown, no code blocks):
-{
-  "friend_score": <integer 1-5>,
-  "coworker_score": <integer 1-5>,
-  "partner_score": <integer 1-5>,
-  "overall_score": <integer 1-5>,
-  "friend_explanation": {
-    "sections": [
-      {
-        "heading": "<Section 1 heading, e.g., 'Cognitive Compatibility & Communication'>",
-        "subcategories": [
-          {
-            "title": "<Sub-category title, e.g., 'Communication Styles' or 'Strengths'>",
-            "bullets": [
-              {"text": "<Detailed bullet point (2-3 sentences worth of information per bullet). Reference specific MBTI traits.>"},
-              {"text": "<Another detailed bullet point (2-3 bullets total per sub-category).>"}
-            ]
-          },
-          {
-            "title": "<Sub-category 2 title, e.g., 'Potential Misunderstandings' or 'Challenges'>",
-            "bullets": [
-              {"text": "<Detailed bullet point.>"},
-              {"text": "<Another detailed bullet point.>"},
-              {"text": "<Third detailed bullet point.>"}
-            ]
-          },
-          {
-            "title": "<Sub-category 3 title, e.g., 'Tips for Better Communication' or 'Growth Opportunities'>",
-            "bullets": [
-              {"text": "<Detailed bullet point.>"},
-              {"text": "<Another detailed bullet point.>"},
-              {"text": "<Third detailed bullet point.>"}
-            ]
-          }
-        ]
-      },
-      {
-        "heading": "<Section 2 heading, e.g., 'Strengths & Synergies'>",
-        "subcategories": [
-          {
-            "title": "<Sub-category title, e.g., 'What Makes Them Great Together'>",
-            "bullets": [
-              {"text": "<2-3 detailed bullets describing complementary traits, shared values, etc.>"}
-            ]
-          }
-        ]
-      },
-      {
-        "heading": "<Section 3 heading, e.g., 'Growth Opportunities & Challenges'>",
-        "subcategories": [
-          {
-            "title": "<Sub-category title>",
-            "bullets": [
-              {"text": "<2-3 detailed bullets>"}
-            ]
-          }
-        ]
-      }
-    ]
-  },
-  "coworker_explanation": {
-    "sections": [
-      {
-        "heading": "<Section 1 heading, e.g., 'Work Style Compatibility'>",
-        "subcategories": [
-          {
-            "title": "<Sub-category title, e.g., 'Complementary Skills' or 'Strengths'>",
-            "bullets": [
-              {"text": "<3-5 detailed bullets on work styles, problem-solving approaches, professional strengths.>"}
-            ]
-          },
-          {
-            "title": "<Sub-category 2 title, e.g., 'Potential Friction Points' or 'Challenges'>",
-            "bullets": [
-              {"text": "<2-3 detailed bullets>"}
-            ]
-          },
-          {
-            "title": "<Sub-category 3 title, e.g., 'Collaboration Tips' or 'Professional Growth'>",
-            "bullets": [
-              {"text": "<2-3 detailed bullets>"}
-            ]
-          }
-        ]
-      },
-      {
-        "heading": "<Section 2 heading, e.g., 'Collaboration Potential'>",
-        "subcategories": [
-          {
-            "title": "<Sub-category title>",
-            "bullets": [
-              {"text": "<2-3 detailed bullets>"}
-            ]
-          }
-        ]
-      },
-      {
-        "heading": "<Section 3 heading, e.g., 'Professional Development & Considerations'>",
-        "subcategories": [
-          {
-            "title": "<Sub-category title>",
-            "bullets": [
-              {"text": "<2-3 detailed bullets>"}
-            ]
-          }
-        ]
-      }
-    ]
-  },
-  "partner_explanation": {
-    "sections": [
-      {
-        "heading": "<Section 1 heading, e.g., 'Romantic Chemistry & Emotional Connection'>",
-        "subcategories": [
-          {
-            "title": "<Sub-category title, e.g., 'What Draws Them Together' (context-specific)>",
-            "bullets": [
-              {"text": "<3-5 detailed bullets on romantic compatibility, emotional intimacy, what attracts them to each other.>"}
-            ]
-          },
-          {
-            "title": "<Sub-category 2 title, e.g., 'Communication Needs' (context-specific)>",
-            "bullets": [
-              {"text": "<2-3 detailed bullets>"}
-            ]
-          },
-          {
-            "title": "<Sub-category 3 title, e.g., 'Success Strategies' (context-specific)>",
-            "bullets": [
-              {"text": "<2-3 detailed bullets>"}
-            ]
-          }
-        ]
-      },
-      {
-        "heading": "<Section 2 heading, e.g., 'Relationship Strengths & Values Alignment'>",
-        "subcategories": [
-          {
-            "title": "<Sub-category title>",
-            "bullets": [
-              {"text": "<2-3 detailed bullets>"}
-            ]
-          }
-        ]
-      },
-      {
-        "heading": "<Section 3 heading, e.g., 'Long-term Potential & Growth Together'>",
-        "subcategories": [
-          {
-            "title": "<Sub-category title>",
-            "bullets": [
-              {"text": "<2-3 detailed bullets>"}
-            ]
-          }
-        ]
-      }
-    ]
-  }
-}
-
-Scoring guidelines:
-- 5: Exceptional compatibility with natural synergy and minimal friction
-- 4: Strong compatibility with minor areas requiring attention
-- 3: Moderate compatibility with some differences that need conscious effort
-- 2: Challenging compatibility requiring significant compromise and understanding
-- 1: Poor compatibility with fundamental conflicts that are difficult to overcome
-
-Be honest, insightful, and provide genuine value. Reference specific MBTI personality traits when relevant. Make each section detailed and actionable.
-
-Return ONLY the raw JSON object, nothing else.`
-
-	return prompt
-}
-
-// buildCategoryPrompt generates a prompt for a single compatibility category
-func buildCategoryPrompt(person1, person2 models.PersonData, category string) string {
-	categoryContext := ""
-
-	switch category {
-	case "friend":
-		categoryContext = "as friends"
-	case "coworker":
-		categoryContext = "as coworkers"
-	case "partner":
-		categoryContext = "as partners in a romantic relationship"
-	default:
-		categoryContext = "in general"
+	payload, err := generateCategoryPayload(ctx, category, prompt, options)
+	if providerUnavailable(err) {
+		return heuristicCategoryResponse(weights, person1, person2, category, options.Language), nil
 	}
-
-	prompt := fmt.Sprintf(`You are a compatibility assessment expert. Analyze the compatibility between two people %s based on ALL the information provided below. You MUST consider and reference their names and MBTI types when making your assessment.
-
-PERSON 1:
-- Name: %s
-- MBTI Type: %s`, categoryContext, person1.Name, person1.MBTI)
-
-	prompt += fmt.Sprintf(`
-
-PERSON 2:
-- Name: %s
-- MBTI Type: %s`, person2.Name, person2.MBTI)
-
-	// Category-specific instructions
-	categoryInstructions := ""
-	switch category {
-	case "friend":
-		categoryInstructions = `
-You are an expert compatibility analyst with deep knowledge of personality psychology, friendship dynamics, and interpersonal communication. Focus specifically on how these two people would interact as FRIENDS. Consider:
-- Communication styles and preferences
-- Shared interests and activities
-- Emotional support and understanding
-- Potential conflicts and how they might resolve them
-- Complementary personality traits that make them great friends
-- Challenges they might face in the friendship`
-	case "coworker":
-		categoryInstructions = `
-You are an expert compatibility analyst with deep knowledge of personality psychology, workplace dynamics, and professional collaboration. Focus specifically on how these two people would interact as COWORKERS. Consider:
-- Work styles and approaches to tasks
-- Communication in professional settings
-- Collaboration and teamwork potential
-- Problem-solving approaches
-- Complementary professional skills
-- Potential workplace conflicts and how they might handle them`
-	case "partner":
-		categoryInstructions = `
-You are an expert compatibility analyst with deep knowledge of personality psychology, romantic relationship dynamics, and emotional intimacy. Focus specifically on how these two people would interact as ROMANTIC PARTNERS. Consider:
-- Romantic chemistry and emotional connection
-- Communication needs and styles in relationships
-- Shared values and life goals
-- Intimacy and emotional support
-- Conflict resolution in romantic relationships
-- Long-term relationship potential`
+	if err != nil {
+		return nil, err
 	}
 
-	prompt += categoryInstructions
-
-	prompt += `
-
-CRITICAL INSTRUCTIONS:
-- You MUST reference and incorporate MBTI types and names in your analysis
-- Focus entirely on the provided MBTI information to deliver the most accurate assessment
-
-Provide a structured analysis with AT LEAST 3 distinct sections. Each section should have:
-- A clear heading
-- 2-3 sub-categories with descriptive titles
-- Each sub-category should contain 2-3 bullet points (detailed, not just one word)
-
-Return your response as a JSON object with the following EXACT structure (no markdown, no code blocks):
-{
-  "score": <integer 1-5>,
-  "explanation": {
-    "sections": [
-      {
-        "heading": "<Section 1 heading, e.g., 'Cognitive Compatibility & Communication'>",
-        "subcategories": [
-          {
-            "title": "<Sub-category title, e.g., 'Communication Styles' or 'Strengths'>",
-            "bullets": [
-              {"text": "<Detailed bullet point (2-3 sentences worth of information per bullet). Reference specific MBTI traits.>"},
-              {"text": "<Another detailed bullet point (2-3 bullets total per sub-category).>"}
-            ]
-          },
-          {
-            "title": "<Sub-category 2 title, e.g., 'Potential Misunderstandings' or 'Challenges'>",
-            "bullets": [
-              {"text": "<Detailed bullet point.>"},
-              {"text": "<Another detailed bullet point.>"},
-              {"text": "<Third detailed bullet point.>"}
-            ]
-          },
-          {
-            "title": "<Sub-category 3 title, e.g., 'Tips for Better Communication' or 'Growth Opportunities'>",
-            "bullets": [
-              {"text": "<Detailed bullet point.>"},
-              {"text": "<Another detailed bullet point.>"},
-              {"text": "<Third detailed bullet point.>"}
-            ]
-          }
-        ]
-      },
-      {
-        "heading": "<Section 2 heading, e.g., 'Strengths & Synergies'>",
-        "subcategories": [
-          {
-            "title": "<Sub-category title, e.g., 'What Makes Them Great Together'>",
-            "bullets": [
-              {"text": "<2-3 detailed bullets describing complementary traits, shared values, etc.>"}
-            ]
-          }
-        ]
-      },
-      {
-        "heading": "<Section 3 heading, e.g., 'Growth Opportunities & Challenges'>",
-        "subcategories": [
-          {
-            "title": "<Sub-category title>",
-            "bullets": [
-              {"text": "<2-3 detailed bullets>"}
-            ]
-          }
-        ]
-      }
-    ]
-  }
-}
-
-Scoring guidelines:
-- 5: Exceptional compatibility with natural synergy and minimal friction
-- 4: Strong compatibility with minor areas requiring attention
-- 3: Moderate compatibility with some differences that need conscious effort
-- 2: Challenging compatibility requiring significant compromise and understanding
-- 1: Poor compatibility with fundamental conflicts that are difficult to overcome
-
-Be honest, insightful, and provide genuine value. Reference specific MBTI personality traits when relevant. Make each section detailed and actionable.
+	cache.storeCategory(payload)
 
-Return ONLY the raw JSON object, nothing else.`
-
-	return prompt
+	return categoryResponseFromPayload(weights, person1, person2, category, payload), nil
 }
 
-// buildCategoryPromptWithBase generates a prompt for a single compatibility category with base explanation for augmentation
-func buildCategoryPromptWithBase(person1, person2 models.PersonData, category string, baseExplanation *models.CategoryExplanation) string {
-	categoryContext := ""
-
-	switch category {
-	case "friend":
-		categoryContext = "as friends"
-	case "coworker":
-		categoryContext = "as coworkers"
-	case "partner":
-		categoryContext = "as partners in a romantic relationship"
-	default:
-		categoryContext = "in general"
+// AssessCategoryCompatibilityWithBase assesses compatibility with optional base explanation for augmentation.
+// When baseExplanation is nil the offline explanation for the MBTI pair is used as the base.
+// additionalContext is free text from the user describing the relationship, empty if none.
+// Samples in options is ignored.
+func AssessCategoryCompatibilityWithBase(ctx context.Context, person1, person2 models.PersonData, category string, baseExplanation *models.CategoryExplanation, additionalContext string, options AssessmentOptions) (*CategoryResponse, error) {
+	if err := validateInput(person1, person2, category); err != nil {
+		return nil, err
 	}
 
-	prompt := fmt.Sprintf(`You are a compatibility assessment expert. Analyze the compatibility between two people %s based on ALL the information provided below. You MUST consider and reference their names and MBTI types when making your assessment.`, categoryContext)
-
-	// If base explanation is provided, add augmentation instructions
-	if baseExplanation != nil {
-		baseJSON, err := json.MarshalIndent(baseExplanation, "", "  ")
-		if err == nil {
-			prompt += fmt.Sprintf(`
-
-IMPORTANT: Below is the BASE compatibility assessment for these MBTI types (based on MBTI compatibility alone):
-
-%s
-
-Your task is to ENHANCE and AUGMENT this base assessment to reflect the specific MBTI pairing of these two individuals.
-- Use the base assessment as a foundation
-- Add new insights and details grounded in the provided MBTI information
-- Maintain a similar structure but expand with context-specific details
-- If the MBTI information doesn't suggest new insights, enhance the base assessment with more depth
-`, string(baseJSON))
-		}
+	options, err := NormalizeOptions(options)
+	if err != nil {
+		return nil, err
 	}
 
-	prompt += fmt.Sprintf(`
-
-PERSON 1:
-- Name: %s
-- MBTI Type: %s`, person1.Name, person1.MBTI)
-
-	prompt += fmt.Sprintf(`
-
-PERSON 2:
-- Name: %s
-- MBTI Type: %s`, person2.Name, person2.MBTI)
-
-	// Category-specific instructions
-	categoryInstructions := ""
-	switch category {
-	case "friend":
-		categoryInstructions = `
-You are an expert compatibility analyst with deep knowledge of personality psychology, friendship dynamics, and interpersonal communication. Focus specifically on how these two people would interact as FRIENDS. Consider:
-- Communication styles and preferences
-- Shared interests and activities
-- Emotional support and understanding
-- Potential conflicts and how they might resolve them
-- Complementary personality traits that make them great friends
-- Challenges they might face in the friendship`
-	case "coworker":
-		categoryInstructions = `
-You are an expert compatibility analyst with deep knowledge of personality psychology, workplace dynamics, and professional collaboration. Focus specifically on how these two people would interact as COWORKERS. Consider:
-- Work styles and approaches to tasks
-- Communication in professional settings
-- Collaboration and teamwork potential
-- Problem-solving approaches
-- Complementary professional skills
-- Potential workplace conflicts and how they might handle them`
-	case "partner":
-		categoryInstructions = `
-You are an expert compatibility analyst with deep knowledge of personality psychology, romantic relationship dynamics, and emotional intimacy. Focus specifically on how these two people would interact as ROMANTIC PARTNERS. Consider:
-- Romantic chemistry and emotional connection
-- Communication needs and styles in relationships
-- Shared values and life goals
-- Intimacy and emotional support
-- Conflict resolution in romantic relationships
-- Long-term relationship potential`
+	additionalContext, err = NormalizeContext(additionalContext)
+	if err != nil {
+		return nil, err
 	}
 
-	prompt += categoryInstructions
-
-	if baseExplanation != nil {
-		prompt += `
-
-CRITICAL AUGMENTATION INSTRUCTIONS:
-- You have been provided with a base MBTI compatibility assessment above
-- Your task is to ENHANCE this assessment by incorporating the MBTI information provided for each person
-- Add depth and detail that contextualizes the MBTI pairing for this specific request
-- Maintain the structure of the base assessment but expand it with context-specific information
-`
-	} else {
-		prompt += `
-
-CRITICAL INSTRUCTIONS:
-- You MUST reference and incorporate MBTI types and names in your analysis
-- Focus entirely on the provided MBTI information to deliver the most accurate assessment
-`
+	if baseExplanation == nil {
+		offline := GenerateOfflineExplanation(person1, person2, category, options.Language)
+		baseExplanation = &offline
 	}
 
-	prompt += `
-Provide a structured analysis with AT LEAST 3 distinct sections. Each section should have:
-- A clear heading
-- 2-3 sub-categories with descriptive titles
-- Each sub-category should contain 2-3 bullet points (detailed, not just one word)
-
-Return your response as a JSON object with the following EXACT structure (no markdown, no code blocks):
-{
-  "score": <integer 1-5>,
-  "explanation": {
-    "sections": [
-      {
-        "heading": "<Section 1 heading, e.g., 'Cognitive Compatibility & Communication'>",
-        "subcategories": [
-          {
-            "title": "<Sub-category title, e.g., 'Communication Styles' or 'Strengths'>",
-            "bullets": [
-              {"text": "<Detailed bullet point (2-3 sentences worth of information per bullet). Reference specific MBTI traits.>"},
-              {"text": "<Another detailed bullet point (2-3 bullets total per sub-category).>"}
-            ]
-          },
-          {
-            "title": "<Sub-category 2 title, e.g., 'Potential Misunderstandings' or 'Challenges'>",
-            "bullets": [
-              {"text": "<Detailed bullet point.>"},
-              {"text": "<Another detailed bullet point.>"},
-              {"text": "<Third detailed bullet point.>"}
-            ]
-          },
-          {
-            "title": "<Sub-category 3 title, e.g., 'Tips for Better Communication' or 'Growth Opportunities'>",
-            "bullets": [
-              {"text": "<Detailed bullet point.>"},
-              {"text": "<Another detailed bullet point.>"},
-              {"text": "<Third detailed bullet point.>"}
-            ]
-          }
-        ]
-      },
-      {
-        "heading": "<Section 2 heading, e.g., 'Strengths & Synergies'>",
-        "subcategories": [
-          {
-            "title": "<Sub-category title, e.g., 'What Makes Them Great Together'>",
-            "bullets": [
-              {"text": "<2-3 detailed bullets describing complementary traits, shared values, etc.>"}
-            ]
-          }
-        ]
-      },
-      {
-        "heading": "<Section 3 heading, e.g., 'Growth Opportunities & Challenges'>",
-        "subcategories": [
-          {
-            "title": "<Sub-category title>",
-            "bullets": [
-              {"text": "<2-3 detailed bullets>"}
-            ]
-          }
-        ]
-      }
-    ]
-  }
-}
-
-Scoring guidelines:
-- 5: Exceptional compatibility with natural synergy and minimal friction
-- 4: Strong compatibility with minor areas requiring attention
-- 3: Moderate compatibility with some differences that need conscious effort
-- 2: Challenging compatibility requiring significant compromise and understanding
-- 1: Poor compatibility with fundamental conflicts that are difficult to overcome
-
-Be honest, insightful, and provide genuine value. Reference specific MBTI personality traits when relevant. Make each section detailed and actionable.
-
-Return ONLY the raw JSON object, nothing else.`
+	weights := currentWeights()
 
-	return prompt
-}
-
-func callGeminiAPI(prompt string) ([]byte, error) {
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("GEMINI_API_KEY environment variable not set")
+	// Not cached: the output depends on the supplied base explanation and context
+	prompt, err := buildCategoryPromptWithBase(person1, person2, category, baseExplanation, additionalContext, options)
+	if err != nil {
+		return nil, err
 	}
 
-	payload := map[string]interface{}{
-		"contents": []map[string]interface{}{
-			{
-				"parts": []map[string]interface{}{
-					{
-						"text": prompt,
-					},
-				},
-			},
-		},
+	payload, err := generateCategoryPayload(ctx, category, prompt, options)
+	if providerUnavailable(err) {
+		return heuristicCategoryResponse(weights, person1, person2, category, options.Language), nil
 	}
-
-	payloadJSON, err := json.Marshal(payload)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, err
 	}
 
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1/models/gemini-2.0-flash:generateContent?key=%s", apiKey)
-	client := &http.Client{}
-
-	maxAttempts := 3
-	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		req, err := http.NewRequest("POST", url, bytes.NewBuffer(payloadJSON))
-		if err != nil {
-			return nil, fmt.Errorf("failed to create request: %w", err)
-		}
-
-		req.Header.Set("Content-Type", "application/json")
-
-		resp, err := client.Do(req)
-		if err != nil {
-			return nil, fmt.Errorf("failed to make request: %w", err)
-		}
-
-		body, readErr := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if readErr != nil {
-			return nil, fmt.Errorf("failed to read response: %w", readErr)
-		}
-
-		if resp.StatusCode == http.StatusOK {
-			return body, nil
-		}
-
-		if (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) && attempt < maxAttempts {
-			wait := time.Duration(1<<uint(attempt-1)) * time.Second
-			time.Sleep(wait)
-			continue
-		}
+	return categoryResponseFromPayload(weights, person1, person2, category, payload), nil
+}
 
-		return nil, fmt.Errorf("API error: status %d, body: %s", resp.StatusCode, string(body))
+// generateCategoryPayload sends a category prompt to the model and decodes its raw (unblended) output
+func generateCategoryPayload(ctx context.Context, category, prompt string, options AssessmentOptions) (*categoryPayload, error) {
+	generated, err := generate(ctx, GenerationRequest{
+		Prompt:          prompt,
+		Schema:          structuredSchema(categorySchema),
+		Category:        category,
+		MaxOutputTokens: options.maxOutputTokens(1),
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("API error: exhausted retries")
+	return decodeCategoryPayload(generated, category, options.Language)
 }
 
-func extractJSON(text string) string {
-	// Try to find JSON in the text
-	startIdx := -1
-	endIdx := -1
-	braceCount := 0
-
-	for i, char := range text {
-		if char == '{' {
-			if startIdx == -1 {
-				startIdx = i
-			}
-			braceCount++
-		} else if char == '}' {
-			braceCount--
-			if braceCount == 0 && startIdx != -1 {
-				endIdx = i + 1
-				break
-			}
-		}
+// decodeCategoryPayload decodes a complete category generation
+func decodeCategoryPayload(generated *GenerationResult, category, language string) (*categoryPayload, error) {
+	decoded, err := decodeModelOutput(generated.Text, decodeTarget{Shape: shapeCategory, Category: category, Language: language, Structured: generated.Structured})
+	if err != nil {
+		return nil, err
 	}
-
-	var jsonText string
-	if startIdx != -1 && endIdx != -1 {
-		jsonText = text[startIdx:endIdx]
-	} else {
-		// If no JSON found, return the whole text (might be just JSON)
-		jsonText = text
+	if err := checkCategoryOutput(decoded.Category); err != nil {
+		return nil, err
 	}
+	return decoded.Category, nil
+}
 
-	// Fix common JSON issues: trailing commas before closing braces/brackets
-	// Use regex-like approach to remove trailing commas more comprehensively
-
-	// Remove trailing comma before } (handle various whitespace patterns)
-	jsonText = strings.ReplaceAll(jsonText, ",}", "}")
-	jsonText = strings.ReplaceAll(jsonText, ", }", " }")
-	jsonText = strings.ReplaceAll(jsonText, ",\n}", "\n}")
-	jsonText = strings.ReplaceAll(jsonText, ",\r\n}", "\r\n}")
-	jsonText = strings.ReplaceAll(jsonText, ",\r}", "\r}")
-	// Handle cases with spaces before comma
-	jsonText = strings.ReplaceAll(jsonText, " ,}", "}")
-	jsonText = strings.ReplaceAll(jsonText, " , }", " }")
-
-	// Remove trailing comma before ] (handle various whitespace patterns)
-	jsonText = strings.ReplaceAll(jsonText, ",]", "]")
-	jsonText = strings.ReplaceAll(jsonText, ", ]", " ]")
-	jsonText = strings.ReplaceAll(jsonText, ",\n]", "\n]")
-	jsonText = strings.ReplaceAll(jsonText, ",\r\n]", "\r\n]")
-	jsonText = strings.ReplaceAll(jsonText, ",\r]", "\r]")
-	// Handle cases with spaces before comma
-	jsonText = strings.ReplaceAll(jsonText, " ,]", "]")
-	jsonText = strings.ReplaceAll(jsonText, " , ]", " ]")
-
-	// More aggressive: remove trailing comma after last quote before closing brace
-	// This handles cases like: "value",\n}
-	jsonBytes := []byte(jsonText)
-	result := []byte{}
-	inString := false
-	escapeNext := false
-
-	for i := 0; i < len(jsonBytes); i++ {
-		char := jsonBytes[i]
-
-		if escapeNext {
-			result = append(result, char)
-			escapeNext = false
-			continue
-		}
-
-		if char == '\\' {
-			escapeNext = true
-			result = append(result, char)
-			continue
-		}
+// categoryResponseFromPayload validates the model score and blends it with the heuristic score
+func categoryResponseFromPayload(weights *scoringWeights, person1, person2 models.PersonData, category string, payload *categoryPayload) *CategoryResponse {
+	details := calculateCategoryDetails(weights, person1, person2, category)
+	score, details := blendDetails(weights, validModelScore(payload.Score), details)
 
-		if char == '"' {
-			inString = !inString
-			result = append(result, char)
-			continue
-		}
+	return &CategoryResponse{
+		Score:          score,
+		Explanation:    payload.Explanation,
+		Source:         llmSource(),
+		PromptVersion:  promptVersion(promptCategory),
+		WeightsVersion: weights.Version,
+		ScoreDetails:   details,
+		PreciseScore:   preciseScore(details),
+	}
+}
 
-		// If we're outside a string and find ",}" or ",\n}" or similar, skip the comma
-		if !inString && char == ',' {
-			// Look ahead to see if next non-whitespace is } or ]
-			j := i + 1
-			for j < len(jsonBytes) {
-				nextChar := jsonBytes[j]
-				if nextChar == ' ' || nextChar == '\n' || nextChar == '\r' || nextChar == '\t' {
-					j++
-					continue
-				}
-				if nextChar == '}' || nextChar == ']' {
-					// Skip this comma, don't append it
-					i = j - 1 // Will be incremented by loop
-					break
-				}
-				// Not a closing brace/bracket, keep the comma
-				result = append(result, char)
-				break
-			}
-			if j >= len(jsonBytes) {
-				result = append(result, char)
-			}
-		} else {
-			result = append(result, char)
-		}
+// validModelScore replaces an out-of-range model score with the neutral 3
+func validModelScore(score int) int {
+	if score < 1 || score > 5 {
+		return 3
 	}
+	return score
+}
 
-	return string(result)
+// OverallScore combines the three category scores into the overall score
+func OverallScore(friend, coworker, partner int) int {
+	return clampScore(float64(friend+coworker+partner) / 3.0)
 }
 
-// cleanJSONForParsing applies additional cleaning passes to ensure valid JSON
-func cleanJSONForParsing(jsonText string) string {
-	// Apply multiple cleaning passes
-	cleaned := jsonText
-
-	// Remove trailing commas more aggressively
-	// Pattern: "value",\n} -> "value"\n}
-	cleaned = strings.ReplaceAll(cleaned, "\",\n}", "\"\n}")
-	cleaned = strings.ReplaceAll(cleaned, "\",\r\n}", "\"\r\n}")
-	cleaned = strings.ReplaceAll(cleaned, "\", }", "\" }")
-	cleaned = strings.ReplaceAll(cleaned, "\",}", "\"}")
-
-	// Remove trailing commas after numbers
-	cleaned = strings.ReplaceAll(cleaned, ",\n}", "\n}")
-	cleaned = strings.ReplaceAll(cleaned, ",\r\n}", "\r\n}")
-	cleaned = strings.ReplaceAll(cleaned, ", }", " }")
-	cleaned = strings.ReplaceAll(cleaned, ",}", "}")
-
-	// Remove trailing commas before closing bracket
-	cleaned = strings.ReplaceAll(cleaned, ",\n]", "\n]")
-	cleaned = strings.ReplaceAll(cleaned, ",\r\n]", "\r\n]")
-	cleaned = strings.ReplaceAll(cleaned, ", ]", " ]")
-	cleaned = strings.ReplaceAll(cleaned, ",]", "]")
-
-	return cleaned
+// llmSource names the active provider for the "source" field of LLM-backed results
+func llmSource() string {
+	provider, err := currentProvider()
+	if err != nil {
+		return "gemini"
+	}
+	return provider.Name()
 }
 
 // convertStringToStructured converts old string format to new structured format with subcategories and bullets
-func convertStringToStructured(text string, category string) models.CategoryExplanation {
+func convertStringToStructured(text string, category, language string) models.CategoryExplanation {
 	sections := []models.ExplanationSection{}
-	headings := getHeadingsForCategory(category)
+	headings := getHeadingsForCategory(category, language)
 
 	// Split text into paragraphs
 	paragraphs := splitIntoParagraphs(text)
@@ -1038,7 +342,7 @@ func convertStringToStructured(text string, category string) models.CategoryExpl
 		// Use paragraphs as sections, convert each to subcategories with bullets
 		for i := 0; i < 3 && i < len(headings); i++ {
 			para := paragraphs[i]
-			subcategories := convertParagraphToSubcategories(para, category, i)
+			subcategories := convertParagraphToSubcategories(para, category, language, i)
 			sections = append(sections, models.ExplanationSection{
 				Heading:       headings[i],
 				Subcategories: subcategories,
@@ -1058,7 +362,7 @@ func convertStringToStructured(text string, category string) models.CategoryExpl
 
 			if start < len(words) {
 				sectionText := strings.Join(words[start:end], " ")
-				subcategories := convertParagraphToSubcategories(sectionText, category, i)
+				subcategories := convertParagraphToSubcategories(sectionText, category, language, i)
 				sections = append(sections, models.ExplanationSection{
 					Heading:       headings[i],
 					Subcategories: subcategories,
@@ -1067,7 +371,7 @@ func convertStringToStructured(text string, category string) models.CategoryExpl
 		}
 	} else {
 		// Fallback: create basic structure from full text
-		subcategories := convertParagraphToSubcategories(text, category, 0)
+		subcategories := convertParagraphToSubcategories(text, category, language, 0)
 		sections = append(sections, models.ExplanationSection{
 			Heading:       headings[0],
 			Subcategories: subcategories,
@@ -1081,9 +385,9 @@ func convertStringToStructured(text string, category string) models.CategoryExpl
 			if idx < len(headings) {
 				subcategories := []models.SubCategory{
 					{
-						Title: "Additional Insights",
+						Title: localeFor(language).additionalInsights,
 						Bullets: []models.BulletPoint{
-							{Text: "Continue reading for more detailed analysis."},
+							{Text: localeFor(language).continueReading},
 						},
 					},
 				}
@@ -1101,7 +405,7 @@ func convertStringToStructured(text string, category string) models.CategoryExpl
 }
 
 // convertParagraphToSubcategories converts a paragraph into subcategories with bullet points
-func convertParagraphToSubcategories(text string, category string, sectionIndex int) []models.SubCategory {
+func convertParagraphToSubcategories(text string, category, language string, sectionIndex int) []models.SubCategory {
 	subcategories := []models.SubCategory{}
 
 	// Split paragraph into sentences
@@ -1109,7 +413,7 @@ func convertParagraphToSubcategories(text string, category string, sectionIndex
 
 	if len(sentences) >= 4 {
 		// Enough sentences - create 2-3 subcategories with 2-3 bullets each
-		subcatTitles := getSubcategoryTitles(category, sectionIndex)
+		subcatTitles := getSubcategoryTitles(category, language, sectionIndex)
 		sentencesPerSubcat := len(sentences) / len(subcatTitles)
 		if sentencesPerSubcat < 2 {
 			sentencesPerSubcat = 2
@@ -1190,7 +494,7 @@ func convertParagraphToSubcategories(text string, category string, sectionIndex
 
 		if len(bullets) > 0 {
 			subcategories = append(subcategories, models.SubCategory{
-				Title:   getDefaultSubcategoryTitle(category, sectionIndex),
+				Title:   getDefaultSubcategoryTitle(category, language, sectionIndex),
 				Bullets: bullets,
 			})
 		}
@@ -1199,7 +503,7 @@ func convertParagraphToSubcategories(text string, category string, sectionIndex
 	// Ensure at least one subcategory
 	if len(subcategories) == 0 {
 		subcategories = append(subcategories, models.SubCategory{
-			Title: "Compatibility Analysis",
+			Title: localeFor(language).analysisTitle,
 			Bullets: []models.BulletPoint{
 				{Text: text},
 			},
@@ -1233,41 +537,21 @@ func splitIntoSentences(text string) []string {
 	return sentences
 }
 
-func getSubcategoryTitles(category string, sectionIndex int) []string {
-	titles := map[string]map[int][]string{
-		"friendship": {
-			0: {"Communication Styles", "Potential Misunderstandings", "Tips for Better Communication"},
-			1: {"What Makes Them Great Together", "Complementary Strengths"},
-			2: {"Growth Opportunities", "Challenges to Navigate"},
-		},
-		"workplace": {
-			0: {"Complementary Skills", "Potential Friction Points", "Collaboration Tips"},
-			1: {"Team Dynamics", "Problem-Solving Approaches"},
-			2: {"Professional Growth", "Considerations"},
-		},
-		"romance": {
-			0: {"What Draws Them Together", "Communication Needs", "Success Strategies"},
-			1: {"Relationship Strengths", "Values Alignment"},
-			2: {"Long-term Potential", "Growth Together"},
-		},
-	}
-
-	if catMap, ok := titles[category]; ok {
-		if sectionTitles, ok := catMap[sectionIndex]; ok {
-			return sectionTitles
-		}
+// getSubcategoryTitles returns the fallback subcategory titles for a section in language
+func getSubcategoryTitles(category, language string, sectionIndex int) []string {
+	texts := localeFor(language)
+	if sectionTitles, ok := texts.subcategoryTitles[category][sectionIndex]; ok {
+		return sectionTitles
 	}
-
-	// Default titles
-	return []string{"Strengths", "Challenges", "Growth Opportunities"}
+	return texts.defaultTitles
 }
 
-func getDefaultSubcategoryTitle(category string, sectionIndex int) string {
-	titles := getSubcategoryTitles(category, sectionIndex)
+func getDefaultSubcategoryTitle(category, language string, sectionIndex int) string {
+	titles := getSubcategoryTitles(category, language, sectionIndex)
 	if len(titles) > 0 {
 		return titles[0]
 	}
-	return "Compatibility Analysis"
+	return localeFor(language).analysisTitle
 }
 
 func splitIntoParagraphs(text string) []string {
@@ -1334,14 +618,11 @@ func splitIntoWords(text string) []string {
 }
 
 // convertSectionsToSubcategories converts old section format (with content string) to new format (with subcategories)
-func convertSectionsToSubcategories(oldSections []struct {
-	Heading string `json:"heading"`
-	Content string `json:"content"`
-}, category string) models.CategoryExplanation {
+func convertSectionsToSubcategories(oldSections []legacySection, category, language string) models.CategoryExplanation {
 	sections := []models.ExplanationSection{}
 
 	for i, oldSection := range oldSections {
-		subcategories := convertParagraphToSubcategories(oldSection.Content, category, i)
+		subcategories := convertParagraphToSubcategories(oldSection.Content, category, language, i)
 		sections = append(sections, models.ExplanationSection{
 			Heading:       oldSection.Heading,
 			Subcategories: subcategories,
@@ -1350,15 +631,15 @@ func convertSectionsToSubcategories(oldSections []struct {
 
 	// Ensure at least 3 sections
 	if len(sections) < 3 {
-		headings := getHeadingsForCategory(category)
+		headings := getHeadingsForCategory(category, language)
 		for len(sections) < 3 {
 			idx := len(sections)
 			if idx < len(headings) {
 				subcategories := []models.SubCategory{
 					{
-						Title: "Additional Insights",
+						Title: localeFor(language).additionalInsights,
 						Bullets: []models.BulletPoint{
-							{Text: "Continue reading for more detailed analysis."},
+							{Text: localeFor(language).continueReading},
 						},
 					},
 				}
@@ -1375,31 +656,11 @@ func convertSectionsToSubcategories(oldSections []struct {
 	return models.CategoryExplanation{Sections: sections}
 }
 
-func getHeadingsForCategory(category string) []string {
-	switch category {
-	case "friendship":
-		return []string{
-			"Cognitive Compatibility & Communication",
-			"Strengths & Synergies",
-			"Growth Opportunities & Challenges",
-		}
-	case "workplace":
-		return []string{
-			"Work Style Compatibility",
-			"Collaboration Potential",
-			"Professional Development & Considerations",
-		}
-	case "romance":
-		return []string{
-			"Romantic Chemistry & Emotional Connection",
-			"Relationship Strengths & Values Alignment",
-			"Long-term Potential & Growth Together",
-		}
-	default:
-		return []string{
-			"Compatibility Analysis",
-			"Key Strengths",
-			"Areas for Growth",
-		}
+// getHeadingsForCategory returns the fallback section headings for category in language
+func getHeadingsForCategory(category, language string) []string {
+	texts := localeFor(language)
+	if headings, ok := texts.headings[category]; ok {
+		return headings
 	}
+	return texts.headings[""]
 }
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+const defaultGeminiModel = "gemini-2.0-flash"
+
+// geminiAPIKeyHeader carries the API key so it stays out of URLs and the logs that record them
+const geminiAPIKeyHeader = "x-goog-api-key"
+
+// geminiProvider talks to the Google Gemini generateContent API
+type geminiProvider struct {
+	keys  *keyPool
+	model string
+}
+
+func newGeminiProviderFromEnv() (*geminiProvider, error) {
+	apiKeys, err := geminiKeysFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if len(apiKeys) == 0 {
+		return nil, fmt.Errorf("GEMINI_API_KEY environment variable not set")
+	}
+
+	keys, err := newKeyPoolFromEnv(apiKeys, geminiAPIKeyHeader)
+	if err != nil {
+		return nil, err
+	}
+	if len(apiKeys) > 1 {
+		log.Printf("Using %d Gemini API keys (%s)", len(apiKeys), keys.strategy)
+	}
+
+	model := strings.TrimSpace(os.Getenv("GEMINI_MODEL"))
+	if model == "" {
+		model = defaultGeminiModel
+	}
+
+	return &geminiProvider{keys: keys, model: model}, nil
+}
+
+func (g *geminiProvider) Name() string {
+	return "gemini"
+}
+
+func (g *geminiProvider) Model() string {
+	return g.model
+}
+
+func (g *geminiProvider) Generate(ctx context.Context, req GenerationRequest) (*GenerationResult, error) {
+	body, err := callGeminiAPI(ctx, g.keys, g.model, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var geminiResp geminiResponse
+	if err := json.Unmarshal(body, &geminiResp); err != nil {
+		return nil, unparseable(fmt.Errorf("failed to parse response: %w", err))
+	}
+
+	usage := geminiResp.UsageMetadata.tokenUsage()
+	if err := geminiResp.blocked(); err != nil {
+		return nil, withUsage(err, usage)
+	}
+
+	if len(geminiResp.Candidates) == 0 {
+		return nil, withUsage(unparseable(fmt.Errorf("no content in response")), usage)
+	}
+
+	candidate := geminiResp.Candidates[0]
+	result := &GenerationResult{
+		Structured:    req.Schema != nil,
+		Usage:         usage,
+		FinishReason:  candidate.FinishReason,
+		SafetyRatings: candidate.SafetyRatings,
+	}
+
+	// A candidate cut off at the token limit may carry no parts at all; the
+	// caller retries it, so only a normal stop without content is an error
+	if len(candidate.Content.Parts) == 0 {
+		if candidate.FinishReason != finishMaxTokens {
+			return nil, withUsage(unparseable(fmt.Errorf("no content in response (finish reason %q)", candidate.FinishReason)), usage)
+		}
+		return result, nil
+	}
+
+	result.Text = candidate.Content.Parts[0].Text
+	return result, nil
+}
+
+func callGeminiAPI(ctx context.Context, keys *keyPool, model string, req GenerationRequest) ([]byte, error) {
+	payloadJSON, err := geminiPayload(req)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent", model)
+	return postJSON(ctx, url, nil, keys, payloadJSON)
+}
+
+// GenerateStream uses streamGenerateContent with server-sent events, passing
+// each candidate text fragment to onChunk as it arrives
+func (g *geminiProvider) GenerateStream(ctx context.Context, req GenerationRequest, onChunk func(string)) (*GenerationResult, error) {
+	payloadJSON, err := geminiPayload(req)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:streamGenerateContent?alt=sse", g.model)
+
+	var text strings.Builder
+	var usage geminiUsage
+	var finishReason string
+	var ratings []SafetyRating
+	err = postJSONStream(ctx, url, nil, g.keys, payloadJSON, func(line []byte) error {
+		data, ok := bytes.CutPrefix(line, []byte("data:"))
+		if !ok {
+			// Blank separators and other SSE fields carry no content
+			return nil
+		}
+
+		var chunk geminiResponse
+		if err := json.Unmarshal(bytes.TrimSpace(data), &chunk); err != nil {
+			return unparseable(fmt.Errorf("failed to parse stream chunk: %w", err))
+		}
+
+		// Each chunk reports cumulative usage, so the last one wins
+		if chunk.UsageMetadata != nil {
+			usage = *chunk.UsageMetadata
+		}
+
+		if err := chunk.blocked(); err != nil {
+			return err
+		}
+
+		if len(chunk.Candidates) == 0 {
+			return nil
+		}
+		candidate := chunk.Candidates[0]
+		if candidate.FinishReason != "" {
+			finishReason = candidate.FinishReason
+		}
+		if len(candidate.SafetyRatings) > 0 {
+			ratings = candidate.SafetyRatings
+		}
+		for _, part := range candidate.Content.Parts {
+			if part.Text == "" {
+				continue
+			}
+			text.WriteString(part.Text)
+			onChunk(part.Text)
+		}
+		return nil
+	})
+	if err != nil {
+		// Output streamed before the failure is billed too
+		return nil, withUsage(err, usage.tokenUsage())
+	}
+
+	return &GenerationResult{
+		Text:          text.String(),
+		Structured:    req.Schema != nil,
+		Usage:         usage.tokenUsage(),
+		FinishReason:  finishReason,
+		SafetyRatings: ratings,
+	}, nil
+}
+
+// geminiResponse is a generateContent response or a single streamGenerateContent chunk
+type geminiResponse struct {
+	Candidates []struct {
+		Content struct {
+			Parts []struct {
+				Text string `json:"text"`
+			} `json:"parts"`
+		} `json:"content"`
+		FinishReason  string         `json:"finishReason"`
+		SafetyRatings []SafetyRating `json:"safetyRatings"`
+	} `json:"candidates"`
+	// PromptFeedback is set when the prompt itself was rejected
+	PromptFeedback *struct {
+		BlockReason   string         `json:"blockReason"`
+		SafetyRatings []SafetyRating `json:"safetyRatings"`
+	} `json:"promptFeedback"`
+	UsageMetadata *geminiUsage `json:"usageMetadata"`
+}
+
+// geminiBlockedFinishReasons are finish reasons for output withheld on safety or policy grounds
+var geminiBlockedFinishReasons = map[string]bool{
+	"SAFETY":             true,
+	"RECITATION":         true,
+	"BLOCKLIST":          true,
+	"PROHIBITED_CONTENT": true,
+	"SPII":               true,
+}
+
+// blocked returns a safety error if the prompt or the first candidate was blocked
+func (r *geminiResponse) blocked() error {
+	if r.PromptFeedback != nil && r.PromptFeedback.BlockReason != "" {
+		return safetyBlocked(r.PromptFeedback.BlockReason, r.PromptFeedback.SafetyRatings)
+	}
+	if len(r.Candidates) > 0 && geminiBlockedFinishReasons[r.Candidates[0].FinishReason] {
+		return safetyBlocked(r.Candidates[0].FinishReason, r.Candidates[0].SafetyRatings)
+	}
+	return nil
+}
+
+// geminiUsage is the usageMetadata block of a Gemini response
+type geminiUsage struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+func (u *geminiUsage) tokenUsage() TokenUsage {
+	if u == nil {
+		return TokenUsage{}
+	}
+	return TokenUsage{
+		PromptTokens:    u.PromptTokenCount,
+		CandidateTokens: u.CandidatesTokenCount,
+		TotalTokens:     u.TotalTokenCount,
+	}
+}
+
+// geminiPayload builds the request body shared by generateContent and streamGenerateContent
+func geminiPayload(req GenerationRequest) ([]byte, error) {
+	payload := map[string]interface{}{
+		"contents": []map[string]interface{}{
+			{
+				"parts": []map[string]interface{}{
+					{
+						"text": req.Prompt,
+					},
+				},
+			},
+		},
+	}
+
+	// Ask Gemini for schema-constrained JSON instead of free text
+	generationConfig := map[string]interface{}{}
+	if req.Schema != nil {
+		generationConfig["responseMimeType"] = "application/json"
+		generationConfig["responseSchema"] = req.Schema
+	}
+	if req.MaxOutputTokens > 0 {
+		generationConfig["maxOutputTokens"] = req.MaxOutputTokens
+	}
+	if len(generationConfig) > 0 {
+		payload["generationConfig"] = generationConfig
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return payloadJSON, nil
+}
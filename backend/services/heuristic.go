@@ -0,0 +1,70 @@
+package services
+
+import (
+	"compatiblah/backend/models"
+	"errors"
+)
+
+// SourceHeuristic marks results computed locally without the LLM
+const SourceHeuristic = "heuristic"
+
+// explanationCategory maps API category names ("friend") to the names used by
+// the explanation helpers ("friendship")
+func explanationCategory(category string) string {
+	switch category {
+	case "friend":
+		return "friendship"
+	case "coworker":
+		return "workplace"
+	case "partner":
+		return "romance"
+	default:
+		return category
+	}
+}
+
+// providerUnavailable reports whether err means no LLM can be used right now,
+// either because none is configured or because the circuit breaker is open
+func providerUnavailable(err error) bool {
+	return errors.Is(err, ErrNoProvider) || errors.Is(err, ErrCircuitOpen)
+}
+
+// heuristicCategoryResponse builds a category result from the local scoring
+// engine and offline explanation generator, used when no LLM provider is available
+func heuristicCategoryResponse(weights *scoringWeights, person1, person2 models.PersonData, category, language string) *CategoryResponse {
+	incMetric("degraded_responses")
+
+	details := calculateCategoryDetails(weights, person1, person2, category)
+	return &CategoryResponse{
+		Score:          details.HeuristicScore,
+		Explanation:    GenerateOfflineExplanation(person1, person2, category, language),
+		Source:         SourceHeuristic,
+		WeightsVersion: weights.Version,
+		ScoreDetails:   details,
+		PreciseScore:   preciseScore(details),
+	}
+}
+
+// heuristicAssessment builds a full assessment from the local scoring engine alone
+func heuristicAssessment(person1, person2 models.PersonData, language string) *AssessmentResult {
+	incMetric("degraded_responses")
+
+	weights := currentWeights()
+	scores, breakdown := calculateCompatibilityScores(weights, person1, person2)
+
+	return &AssessmentResult{
+		GeminiResponse: models.GeminiResponse{
+			FriendScore:         scores.Friend,
+			CoworkerScore:       scores.Coworker,
+			PartnerScore:        scores.Partner,
+			OverallScore:        OverallScore(scores.Friend, scores.Coworker, scores.Partner),
+			FriendExplanation:   GenerateOfflineExplanation(person1, person2, "friend", language),
+			CoworkerExplanation: GenerateOfflineExplanation(person1, person2, "coworker", language),
+			PartnerExplanation:  GenerateOfflineExplanation(person1, person2, "partner", language),
+		},
+		Source:         SourceHeuristic,
+		WeightsVersion: weights.Version,
+		ScoreDetails:   breakdown,
+		PreciseScores:  preciseScores(breakdown),
+	}
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Key selection strategies for GEMINI_KEY_STRATEGY
+const (
+	keyStrategyRoundRobin     = "round-robin"
+	keyStrategyLeastThrottled = "least-throttled"
+)
+
+// apiKey is one key in a keyPool
+type apiKey struct {
+	value string
+	// quarantinedUntil is when the key may be used again after a 429 or 403
+	quarantinedUntil time.Time
+	// lastThrottled is when the key last returned a 429 or 403, zero if never
+	lastThrottled time.Time
+}
+
+// label identifies the key in logs without revealing it
+func (k *apiKey) label() string {
+	if len(k.value) <= 4 {
+		return "…"
+	}
+	return "…" + k.value[len(k.value)-4:]
+}
+
+// keyPool hands out API keys per request attempt and takes keys that were
+// throttled (429) or refused (403) out of rotation for a while
+type keyPool struct {
+	mu       sync.Mutex
+	keys     []*apiKey
+	next     int
+	strategy string
+	// header is the request header that carries the key
+	header string
+	// throttledFor and forbiddenFor are the quarantine periods after a 429 and a 403
+	throttledFor time.Duration
+	forbiddenFor time.Duration
+}
+
+// geminiKeysFromEnv collects Gemini API keys from GEMINI_API_KEYS (comma
+// separated), GEMINI_API_KEYS_FILE (one per line, # comments) and
+// GEMINI_API_KEY, dropping duplicates
+func geminiKeysFromEnv() ([]string, error) {
+	var raw []string
+	raw = append(raw, strings.Split(os.Getenv("GEMINI_API_KEYS"), ",")...)
+
+	if path := strings.TrimSpace(os.Getenv("GEMINI_API_KEYS_FILE")); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read GEMINI_API_KEYS_FILE: %w", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.HasPrefix(strings.TrimSpace(line), "#") {
+				continue
+			}
+			raw = append(raw, line)
+		}
+	}
+
+	raw = append(raw, os.Getenv("GEMINI_API_KEY"))
+
+	seen := make(map[string]bool)
+	var keys []string
+	for _, key := range raw {
+		key = strings.TrimSpace(key)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// geminiKeysConfigured reports whether any Gemini key source is set
+func geminiKeysConfigured() bool {
+	for _, name := range []string{"GEMINI_API_KEY", "GEMINI_API_KEYS", "GEMINI_API_KEYS_FILE"} {
+		if strings.TrimSpace(os.Getenv(name)) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// newKeyPoolFromEnv builds a pool over keys sent in header, configured by
+// GEMINI_KEY_STRATEGY, GEMINI_KEY_QUARANTINE and GEMINI_KEY_FORBIDDEN_QUARANTINE
+func newKeyPoolFromEnv(keys []string, header string) (*keyPool, error) {
+	strategy := strings.ToLower(strings.TrimSpace(os.Getenv("GEMINI_KEY_STRATEGY")))
+	switch strategy {
+	case "":
+		strategy = keyStrategyRoundRobin
+	case keyStrategyRoundRobin, keyStrategyLeastThrottled:
+	default:
+		return nil, fmt.Errorf("unknown GEMINI_KEY_STRATEGY %q (expected %s or %s)", strategy, keyStrategyRoundRobin, keyStrategyLeastThrottled)
+	}
+
+	pool := &keyPool{
+		strategy:     strategy,
+		header:       header,
+		throttledFor: envDuration("GEMINI_KEY_QUARANTINE", time.Minute),
+		forbiddenFor: envDuration("GEMINI_KEY_FORBIDDEN_QUARANTINE", 15*time.Minute),
+	}
+	for _, key := range keys {
+		pool.keys = append(pool.keys, &apiKey{value: key})
+	}
+	return pool, nil
+}
+
+// acquire picks the key for the next attempt. When every key is quarantined it
+// returns a transient rate limit error asking to retry once the first key is released.
+func (p *keyPool) acquire() (*apiKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var chosen *apiKey
+	chosenIndex := -1
+	for offset := range p.keys {
+		index := (p.next + offset) % len(p.keys)
+		key := p.keys[index]
+		if now.Before(key.quarantinedUntil) {
+			continue
+		}
+		if chosen == nil || (p.strategy == keyStrategyLeastThrottled && key.lastThrottled.Before(chosen.lastThrottled)) {
+			chosen, chosenIndex = key, index
+		}
+		if p.strategy == keyStrategyRoundRobin {
+			break
+		}
+	}
+
+	if chosen == nil {
+		release := p.keys[0].quarantinedUntil
+		for _, key := range p.keys[1:] {
+			if key.quarantinedUntil.Before(release) {
+				release = key.quarantinedUntil
+			}
+		}
+		err := newError(ErrRateLimited, "The assessment service is busy, please try again shortly",
+			fmt.Errorf("all %d API keys are quarantined", len(p.keys)))
+		return nil, &transientError{err: err, after: release.Sub(now)}
+	}
+
+	p.next = (chosenIndex + 1) % len(p.keys)
+	return chosen, nil
+}
+
+// report records the response status for key and returns true when the key
+// was quarantined while another key is still available to retry with. The last
+// available key is never quarantined for a 403 and only for the server's retry
+// delay after a 429, so a single-key deployment keeps retrying on the normal
+// backoff and a bad key is reported as rejected rather than busy.
+func (p *keyPool) report(key *apiKey, statusCode int, header http.Header, body []byte) bool {
+	if statusCode != http.StatusTooManyRequests && statusCode != http.StatusForbidden {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	othersAvailable := false
+	for _, other := range p.keys {
+		if other != key && !now.Before(other.quarantinedUntil) {
+			othersAvailable = true
+			break
+		}
+	}
+
+	period := p.forbiddenFor
+	switch {
+	case statusCode == http.StatusTooManyRequests && othersAvailable:
+		period = max(p.throttledFor, serverRetryDelay(header, body))
+	case statusCode == http.StatusTooManyRequests:
+		period = serverRetryDelay(header, body)
+	case !othersAvailable:
+		// Keep the last key so a bad key surfaces as the provider's rejection,
+		// not as every key being busy
+		period = 0
+	}
+
+	key.lastThrottled = now
+	if period <= 0 {
+		return false
+	}
+
+	key.quarantinedUntil = now.Add(period)
+	incMetric("api_keys_quarantined")
+	log.Printf("Quarantining API key %s for %s after status %d", key.label(), period, statusCode)
+	return othersAvailable
+}
+
+// headers returns the request headers for one attempt with a key from the pool
+// and a callback for the attempt's response status. A nil pool adds nothing.
+func (p *keyPool) headers(base map[string]string) (map[string]string, func(int, http.Header, []byte) bool, error) {
+	if p == nil {
+		return base, func(int, http.Header, []byte) bool { return false }, nil
+	}
+
+	key, err := p.acquire()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attempt := make(map[string]string, len(base)+1)
+	for name, value := range base {
+		attempt[name] = value
+	}
+	attempt[p.header] = key.value
+
+	return attempt, func(statusCode int, header http.Header, body []byte) bool {
+		return p.report(key, statusCode, header, body)
+	}, nil
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"fmt"
+	"golang.org/x/text/language"
+	"strings"
+)
+
+// defaultLanguage is used when a request does not ask for a language
+const defaultLanguage = "en"
+
+// locale is the fixed text of one supported language: the name the prompts use
+// for it and the strings used when explanations are built without the model.
+// Headings and subcategory titles are keyed by explanation category ("friendship"),
+// with "" holding the defaults.
+type locale struct {
+	code string
+	tag  language.Tag
+	// name is the English name of the language, as the prompts refer to it
+	name              string
+	headings          map[string][]string
+	subcategoryTitles map[string]map[int][]string
+	defaultTitles     []string
+	// analysisTitle titles a subcategory when nothing more specific fits
+	analysisTitle      string
+	additionalInsights string
+	continueReading    string
+	// offlineSummary is the generic offline explanation, one sentence per section;
+	// the first is formatted with both names
+	offlineSummary []string
+}
+
+// locales are the supported languages. The first is the fallback for unknown codes.
+var locales = []*locale{
+	{
+		code: "en",
+		tag:  language.English,
+		name: "English",
+		headings: map[string][]string{
+			"friendship": {"Cognitive Compatibility & Communication", "Strengths & Synergies", "Growth Opportunities & Challenges"},
+			"workplace":  {"Work Style Compatibility", "Collaboration Potential", "Professional Development & Considerations"},
+			"romance":    {"Romantic Chemistry & Emotional Connection", "Relationship Strengths & Values Alignment", "Long-term Potential & Growth Together"},
+			"":           {"Compatibility Analysis", "Key Strengths", "Areas for Growth"},
+		},
+		subcategoryTitles: map[string]map[int][]string{
+			"friendship": {
+				0: {"Communication Styles", "Potential Misunderstandings", "Tips for Better Communication"},
+				1: {"What Makes Them Great Together", "Complementary Strengths"},
+				2: {"Growth Opportunities", "Challenges to Navigate"},
+			},
+			"workplace": {
+				0: {"Complementary Skills", "Potential Friction Points", "Collaboration Tips"},
+				1: {"Team Dynamics", "Problem-Solving Approaches"},
+				2: {"Professional Growth", "Considerations"},
+			},
+			"romance": {
+				0: {"What Draws Them Together", "Communication Needs", "Success Strategies"},
+				1: {"Relationship Strengths", "Values Alignment"},
+				2: {"Long-term Potential", "Growth Together"},
+			},
+		},
+		defaultTitles:      []string{"Strengths", "Challenges", "Growth Opportunities"},
+		analysisTitle:      "Compatibility Analysis",
+		additionalInsights: "Additional Insights",
+		continueReading:    "Continue reading for more detailed analysis.",
+		offlineSummary: []string{
+			"%s and %s bring their own perspectives to this relationship.",
+			"A detailed analysis is not available for this combination, so this summary is based on general compatibility patterns.",
+			"Open communication and curiosity about each other's preferences will help them build a strong connection.",
+		},
+	},
+	{
+		code: "es",
+		tag:  language.Spanish,
+		name: "Spanish",
+		headings: map[string][]string{
+			"friendship": {"Compatibilidad cognitiva y comunicación", "Fortalezas y sinergias", "Oportunidades de crecimiento y desafíos"},
+			"workplace":  {"Compatibilidad de estilos de trabajo", "Potencial de colaboración", "Desarrollo profesional y consideraciones"},
+			"romance":    {"Química romántica y conexión emocional", "Fortalezas de la relación y afinidad de valores", "Potencial a largo plazo y crecimiento conjunto"},
+			"":           {"Análisis de compatibilidad", "Fortalezas clave", "Áreas de crecimiento"},
+		},
+		subcategoryTitles: map[string]map[int][]string{
+			"friendship": {
+				0: {"Estilos de comunicación", "Posibles malentendidos", "Consejos para comunicarse mejor"},
+				1: {"Lo que los hace geniales juntos", "Fortalezas complementarias"},
+				2: {"Oportunidades de crecimiento", "Desafíos a superar"},
+			},
+			"workplace": {
+				0: {"Habilidades complementarias", "Posibles puntos de fricción", "Consejos de colaboración"},
+				1: {"Dinámica de equipo", "Enfoques para resolver problemas"},
+				2: {"Crecimiento profesional", "Consideraciones"},
+			},
+			"romance": {
+				0: {"Lo que los une", "Necesidades de comunicación", "Estrategias para el éxito"},
+				1: {"Fortalezas de la relación", "Afinidad de valores"},
+				2: {"Potencial a largo plazo", "Crecer juntos"},
+			},
+		},
+		defaultTitles:      []string{"Fortalezas", "Desafíos", "Oportunidades de crecimiento"},
+		analysisTitle:      "Análisis de compatibilidad",
+		additionalInsights: "Perspectivas adicionales",
+		continueReading:    "Sigue leyendo para un análisis más detallado.",
+		offlineSummary: []string{
+			"%s y %s aportan cada uno su propia perspectiva a esta relación.",
+			"Este resumen se basa en patrones generales de compatibilidad.",
+			"La comunicación abierta y la curiosidad por las preferencias del otro les ayudarán a construir una conexión sólida.",
+		},
+	},
+	{
+		code: "fr",
+		tag:  language.French,
+		name: "French",
+		headings: map[string][]string{
+			"friendship": {"Compatibilité cognitive et communication", "Forces et synergies", "Pistes d'évolution et défis"},
+			"workplace":  {"Compatibilité des styles de travail", "Potentiel de collaboration", "Développement professionnel et points d'attention"},
+			"romance":    {"Alchimie amoureuse et lien émotionnel", "Forces du couple et valeurs communes", "Potentiel à long terme et évolution commune"},
+			"":           {"Analyse de compatibilité", "Points forts", "Axes de progression"},
+		},
+		subcategoryTitles: map[string]map[int][]string{
+			"friendship": {
+				0: {"Styles de communication", "Malentendus possibles", "Conseils pour mieux communiquer"},
+				1: {"Ce qui les rend formidables ensemble", "Forces complémentaires"},
+				2: {"Pistes d'évolution", "Défis à relever"},
+			},
+			"workplace": {
+				0: {"Compétences complémentaires", "Points de friction possibles", "Conseils de collaboration"},
+				1: {"Dynamique d'équipe", "Approches de résolution de problèmes"},
+				2: {"Évolution professionnelle", "Points d'attention"},
+			},
+			"romance": {
+				0: {"Ce qui les attire l'un vers l'autre", "Besoins de communication", "Clés de réussite"},
+				1: {"Forces du couple", "Valeurs communes"},
+				2: {"Potentiel à long terme", "Grandir ensemble"},
+			},
+		},
+		defaultTitles:      []string{"Points forts", "Défis", "Pistes d'évolution"},
+		analysisTitle:      "Analyse de compatibilité",
+		additionalInsights: "Perspectives complémentaires",
+		continueReading:    "Poursuivez la lecture pour une analyse plus détaillée.",
+		offlineSummary: []string{
+			"%s et %s apportent chacun leur propre regard à cette relation.",
+			"Ce résumé s'appuie sur des tendances générales de compatibilité.",
+			"Une communication ouverte et la curiosité envers les préférences de l'autre les aideront à bâtir un lien solide.",
+		},
+	},
+	{
+		code: "de",
+		tag:  language.German,
+		name: "German",
+		headings: map[string][]string{
+			"friendship": {"Kognitive Kompatibilität & Kommunikation", "Stärken & Synergien", "Entwicklungschancen & Herausforderungen"},
+			"workplace":  {"Kompatibilität der Arbeitsstile", "Potenzial für Zusammenarbeit", "Berufliche Entwicklung & Überlegungen"},
+			"romance":    {"Romantische Chemie & emotionale Verbindung", "Stärken der Beziehung & gemeinsame Werte", "Langfristiges Potenzial & gemeinsames Wachstum"},
+			"":           {"Kompatibilitätsanalyse", "Wichtigste Stärken", "Entwicklungsfelder"},
+		},
+		subcategoryTitles: map[string]map[int][]string{
+			"friendship": {
+				0: {"Kommunikationsstile", "Mögliche Missverständnisse", "Tipps für bessere Kommunikation"},
+				1: {"Was sie zusammen großartig macht", "Sich ergänzende Stärken"},
+				2: {"Entwicklungschancen", "Herausforderungen"},
+			},
+			"workplace": {
+				0: {"Sich ergänzende Fähigkeiten", "Mögliche Reibungspunkte", "Tipps für die Zusammenarbeit"},
+				1: {"Teamdynamik", "Lösungsansätze"},
+				2: {"Berufliches Wachstum", "Überlegungen"},
+			},
+			"romance": {
+				0: {"Was sie zueinander hinzieht", "Kommunikationsbedürfnisse", "Erfolgsstrategien"},
+				1: {"Stärken der Beziehung", "Gemeinsame Werte"},
+				2: {"Langfristiges Potenzial", "Gemeinsam wachsen"},
+			},
+		},
+		defaultTitles:      []string{"Stärken", "Herausforderungen", "Entwicklungschancen"},
+		analysisTitle:      "Kompatibilitätsanalyse",
+		additionalInsights: "Weitere Einblicke",
+		continueReading:    "Lies weiter für eine ausführlichere Analyse.",
+		offlineSummary: []string{
+			"%s und %s bringen jeweils ihre eigene Perspektive in diese Beziehung ein.",
+			"Diese Zusammenfassung beruht auf allgemeinen Kompatibilitätsmustern.",
+			"Offene Kommunikation und Neugier auf die Vorlieben des anderen helfen ihnen, eine starke Verbindung aufzubauen.",
+		},
+	},
+	{
+		code: "id",
+		tag:  language.Indonesian,
+		name: "Indonesian",
+		headings: map[string][]string{
+			"friendship": {"Kecocokan Kognitif & Komunikasi", "Kekuatan & Sinergi", "Peluang Berkembang & Tantangan"},
+			"workplace":  {"Kecocokan Gaya Kerja", "Potensi Kolaborasi", "Pengembangan Profesional & Pertimbangan"},
+			"romance":    {"Ketertarikan Romantis & Ikatan Emosional", "Kekuatan Hubungan & Keselarasan Nilai", "Potensi Jangka Panjang & Tumbuh Bersama"},
+			"":           {"Analisis Kecocokan", "Kekuatan Utama", "Area untuk Berkembang"},
+		},
+		subcategoryTitles: map[string]map[int][]string{
+			"friendship": {
+				0: {"Gaya Komunikasi", "Potensi Salah Paham", "Tips Komunikasi yang Lebih Baik"},
+				1: {"Yang Membuat Mereka Cocok", "Kekuatan yang Saling Melengkapi"},
+				2: {"Peluang Berkembang", "Tantangan yang Perlu Dihadapi"},
+			},
+			"workplace": {
+				0: {"Keterampilan yang Saling Melengkapi", "Potensi Gesekan", "Tips Kolaborasi"},
+				1: {"Dinamika Tim", "Pendekatan Pemecahan Masalah"},
+				2: {"Pertumbuhan Profesional", "Pertimbangan"},
+			},
+			"romance": {
+				0: {"Yang Membuat Mereka Tertarik", "Kebutuhan Komunikasi", "Strategi Sukses"},
+				1: {"Kekuatan Hubungan", "Keselarasan Nilai"},
+				2: {"Potensi Jangka Panjang", "Tumbuh Bersama"},
+			},
+		},
+		defaultTitles:      []string{"Kekuatan", "Tantangan", "Peluang Berkembang"},
+		analysisTitle:      "Analisis Kecocokan",
+		additionalInsights: "Wawasan Tambahan",
+		continueReading:    "Lanjutkan membaca untuk analisis yang lebih mendalam.",
+		offlineSummary: []string{
+			"%s dan %s masing-masing membawa sudut pandang sendiri ke dalam hubungan ini.",
+			"Ringkasan ini didasarkan pada pola kecocokan secara umum.",
+			"Komunikasi yang terbuka dan rasa ingin tahu terhadap preferensi satu sama lain akan membantu mereka membangun hubungan yang kuat.",
+		},
+	},
+}
+
+var languageMatcher = func() language.Matcher {
+	tags := make([]language.Tag, len(locales))
+	for i, l := range locales {
+		tags[i] = l.tag
+	}
+	return language.NewMatcher(tags)
+}()
+
+// NormalizeLanguage maps a BCP-47 tag such as "es-MX" to the code of the
+// closest supported language. An empty tag selects the default language.
+func NormalizeLanguage(tag string) (string, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return defaultLanguage, nil
+	}
+
+	parsed, err := language.Parse(tag)
+	if err != nil {
+		return "", newError(ErrInvalidInput, fmt.Sprintf("Language %q is not a valid language tag", tag), err)
+	}
+
+	_, index, confidence := languageMatcher.Match(parsed)
+	if confidence == language.No {
+		return "", newError(ErrInvalidInput, "Language must be one of "+strings.Join(SupportedLanguages(), ", "), nil)
+	}
+	return locales[index].code, nil
+}
+
+// SupportedLanguages returns the codes of the supported languages
+func SupportedLanguages() []string {
+	codes := make([]string, len(locales))
+	for i, l := range locales {
+		codes[i] = l.code
+	}
+	return codes
+}
+
+// localeFor returns the locale for a normalized language code, falling back to
+// English for empty or unknown codes
+func localeFor(code string) *locale {
+	for _, l := range locales {
+		if l.code == code {
+			return l
+		}
+	}
+	return locales[0]
+}
+
+// promptLanguage is the language name the prompts ask the model to write in,
+// empty for English so English prompts are unchanged
+func promptLanguage(code string) string {
+	if l := localeFor(code); l.code != defaultLanguage {
+		return l.name
+	}
+	return ""
+}
@@ -0,0 +1,18 @@
+package services
+
+import "expvar"
+
+// metrics holds service counters, published through expvar under "compatiblah"
+var metrics = expvar.NewMap("compatiblah")
+
+// incMetric increments the named counter by one
+func incMetric(name string) {
+	metrics.Add(name, 1)
+}
+
+// incMetricBy adds delta to the named counter
+func incMetricBy(name string, delta int) {
+	if delta != 0 {
+		metrics.Add(name, int64(delta))
+	}
+}
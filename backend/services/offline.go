@@ -0,0 +1,205 @@
+package services
+
+import (
+	"compatiblah/backend/models"
+	"fmt"
+	"strings"
+)
+
+// offlineSlot describes which insight fills a subcategory in the offline layout
+type offlineSlot struct {
+	kind       string // "strength", "challenge", "tip" or "overview"
+	dimensions []string
+}
+
+// offlineLayout mirrors the section/subcategory titles from getSubcategoryTitles:
+// three subcategories in the first section and two in the others
+var offlineLayout = [][]offlineSlot{
+	{
+		{kind: "strength", dimensions: []string{"energy", "intuition"}},
+		{kind: "challenge", dimensions: []string{"energy", "intuition"}},
+		{kind: "tip", dimensions: []string{"energy", "intuition"}},
+	},
+	{
+		{kind: "strength", dimensions: []string{"decision", "lifestyle"}},
+		{kind: "overview"},
+	},
+	{
+		{kind: "tip", dimensions: []string{"decision", "lifestyle"}},
+		{kind: "challenge", dimensions: []string{"decision", "lifestyle"}},
+	},
+}
+
+// dimensionLabels names each dimension for overview bullets
+var dimensionLabels = map[string]string{
+	"energy":    "energy (E/I)",
+	"intuition": "information (N/S)",
+	"decision":  "decisions (T/F)",
+	"lifestyle": "structure (J/P)",
+}
+
+var dimensionOrder = []string{"energy", "intuition", "decision", "lifestyle"}
+
+// GenerateOfflineExplanation builds a deterministic category explanation from the
+// curated per-dimension knowledge base, without calling an LLM. The result can be
+// served directly or passed as the base to AssessCategoryCompatibilityWithBase.
+// The knowledge base is English; other languages get a localized general summary.
+func GenerateOfflineExplanation(person1, person2 models.PersonData, category, language string) models.CategoryExplanation {
+	explCategory := explanationCategory(category)
+	knowledge, hasKnowledge := offlineKnowledge[explCategory]
+	profile1, ok1 := parseMBTIProfile(person1.MBTI)
+	profile2, ok2 := parseMBTIProfile(person2.MBTI)
+
+	if !hasKnowledge || !ok1 || !ok2 || localeFor(language).code != defaultLanguage {
+		return offlineSummaryExplanation(person1, person2, explCategory, language)
+	}
+
+	headings := getHeadingsForCategory(explCategory, language)
+	sections := []models.ExplanationSection{}
+
+	for sectionIndex, slots := range offlineLayout {
+		titles := getSubcategoryTitles(explCategory, language, sectionIndex)
+		subcategories := []models.SubCategory{}
+
+		for slotIndex, slot := range slots {
+			if slotIndex >= len(titles) {
+				break
+			}
+
+			var bullets []models.BulletPoint
+			if slot.kind == "overview" {
+				bullets = overviewBullets(person1, person2, profile1, profile2, explCategory)
+			} else {
+				for _, dimension := range slot.dimensions {
+					text := insightText(knowledge, dimension, slot.kind, person1.Name, person2.Name, profile1, profile2)
+					if text != "" {
+						bullets = append(bullets, models.BulletPoint{Text: text})
+					}
+				}
+			}
+
+			subcategories = append(subcategories, models.SubCategory{
+				Title:   titles[slotIndex],
+				Bullets: bullets,
+			})
+		}
+
+		sections = append(sections, models.ExplanationSection{
+			Heading:       headings[sectionIndex],
+			Subcategories: subcategories,
+		})
+	}
+
+	return models.CategoryExplanation{Sections: sections}
+}
+
+// offlineSummaryExplanation lays the localized general summary out with one
+// whole sentence per section, under the localized headings and titles
+func offlineSummaryExplanation(person1, person2 models.PersonData, explCategory, language string) models.CategoryExplanation {
+	sentences := localeFor(language).offlineSummary
+	headings := getHeadingsForCategory(explCategory, language)
+	sections := []models.ExplanationSection{}
+
+	for i, sentence := range sentences {
+		if i >= len(headings) {
+			break
+		}
+		if i == 0 {
+			sentence = fmt.Sprintf(sentence, person1.Name, person2.Name)
+		}
+
+		sections = append(sections, models.ExplanationSection{
+			Heading: headings[i],
+			Subcategories: []models.SubCategory{{
+				Title:   getDefaultSubcategoryTitle(explCategory, language, i),
+				Bullets: []models.BulletPoint{{Text: sentence}},
+			}},
+		})
+	}
+
+	return models.CategoryExplanation{Sections: sections}
+}
+
+// insightText picks and fills the knowledge base entry for one dimension
+func insightText(knowledge map[string]map[string]dimensionInsight, dimension, kind, name1, name2 string, profile1, profile2 mbtiProfile) string {
+	letter1 := dimensionLetter(profile1, dimension)
+	letter2 := dimensionLetter(profile2, dimension)
+
+	relation := string(letter1)
+	x, y := name1, name2
+	if letter1 != letter2 {
+		relation = "mixed"
+		// {x} always holds the E/N/T/J preference
+		if !strings.ContainsRune("ENTJ", letter1) {
+			x, y = name2, name1
+		}
+	}
+
+	insight, ok := knowledge[dimension][relation]
+	if !ok {
+		return ""
+	}
+
+	var template string
+	switch kind {
+	case "strength":
+		template = insight.strength
+	case "challenge":
+		template = insight.challenge
+	case "tip":
+		template = insight.tip
+	}
+
+	replacer := strings.NewReplacer("{a}", name1, "{b}", name2, "{x}", x, "{y}", y)
+	return replacer.Replace(template)
+}
+
+// overviewBullets summarises shared and differing preferences
+func overviewBullets(person1, person2 models.PersonData, profile1, profile2 mbtiProfile, explCategory string) []models.BulletPoint {
+	shared := []string{}
+	different := []string{}
+	for _, dimension := range dimensionOrder {
+		if dimensionLetter(profile1, dimension) == dimensionLetter(profile2, dimension) {
+			shared = append(shared, dimensionLabels[dimension])
+		} else {
+			different = append(different, dimensionLabels[dimension])
+		}
+	}
+
+	relationship := map[string]string{
+		"friendship": "friendship",
+		"workplace":  "working relationship",
+		"romance":    "relationship",
+	}[explCategory]
+
+	type1 := strings.ToUpper(strings.TrimSpace(person1.MBTI))
+	type2 := strings.ToUpper(strings.TrimSpace(person2.MBTI))
+
+	bullets := []models.BulletPoint{}
+	if len(shared) > 0 {
+		bullets = append(bullets, models.BulletPoint{Text: fmt.Sprintf("%s (%s) and %s (%s) share %d of 4 preferences: %s, which gives their %s a natural common ground.", person1.Name, type1, person2.Name, type2, len(shared), strings.Join(shared, ", "), relationship)})
+	} else {
+		bullets = append(bullets, models.BulletPoint{Text: fmt.Sprintf("%s (%s) and %s (%s) are opposites on every preference, so their %s thrives on curiosity about how the other sees the world.", person1.Name, type1, person2.Name, type2, relationship)})
+	}
+
+	if len(different) > 0 && len(shared) > 0 {
+		bullets = append(bullets, models.BulletPoint{Text: fmt.Sprintf("Their differences in %s are where each covers the other's blind spots.", strings.Join(different, ", "))})
+	} else if len(different) == 0 {
+		bullets = append(bullets, models.BulletPoint{Text: "As the same type they understand each other intuitively, but they may also share the same blind spots."})
+	}
+
+	return bullets
+}
+
+func dimensionLetter(profile mbtiProfile, dimension string) rune {
+	switch dimension {
+	case "energy":
+		return profile.energy
+	case "intuition":
+		return profile.intuition
+	case "decision":
+		return profile.decision
+	default:
+		return profile.lifestyle
+	}
+}
@@ -0,0 +1,226 @@
+package services
+
+// dimensionInsight is curated copy for one MBTI dimension interaction within a category.
+// Placeholders: {a} and {b} are the two names when both share the preference;
+// {x} is the person with E/N/T/J and {y} the person with I/S/F/P when they differ.
+type dimensionInsight struct {
+	strength  string
+	challenge string
+	tip       string
+}
+
+// offlineKnowledge is indexed by explanation category ("friendship", "workplace",
+// "romance"), then dimension ("energy", "intuition", "decision", "lifestyle"), then
+// relation: the shared letter ("E", "I", ...) or "mixed".
+var offlineKnowledge = map[string]map[string]map[string]dimensionInsight{
+	"friendship": {
+		"energy": {
+			"E": {
+				strength:  "{a} and {b} both recharge around people, so plans come together easily and there is rarely a dull weekend.",
+				challenge: "With two extraverts, conversations can turn into a contest for airtime and quieter moments get skipped.",
+				tip:       "Take turns choosing activities and leave room for one-on-one time, not just group outings.",
+			},
+			"I": {
+				strength:  "{a} and {b} both value quiet, low-key time together and understand when the other needs space.",
+				challenge: "Neither is likely to reach out first, so the friendship can drift during busy periods.",
+				tip:       "Set a simple recurring ritual, like a monthly coffee, so staying in touch does not depend on initiative.",
+			},
+			"mixed": {
+				strength:  "{x} pulls {y} into new experiences while {y} offers {x} calm, focused conversation.",
+				challenge: "{x} may read {y}'s need for downtime as disinterest, and {y} may find {x}'s pace tiring.",
+				tip:       "Agree on a balance of social plans and quiet hangouts, and say openly when energy is running low.",
+			},
+		},
+		"intuition": {
+			"N": {
+				strength:  "Both love ideas and what-ifs, so conversations between {a} and {b} easily run late into the night.",
+				challenge: "Big plans can stay theoretical when neither wants to handle the practical details.",
+				tip:       "Pick one shared idea each season and actually book it, so the dreaming turns into memories.",
+			},
+			"S": {
+				strength:  "{a} and {b} enjoy concrete, hands-on activities and notice the same practical details.",
+				challenge: "Routines can become comfortable to the point of predictable.",
+				tip:       "Try one unfamiliar activity together every so often to keep the friendship fresh.",
+			},
+			"mixed": {
+				strength:  "{x} brings imaginative ideas while {y} grounds them in what is realistic and fun right now.",
+				challenge: "{x} may find {y}'s focus on specifics limiting, while {y} may see {x}'s tangents as impractical.",
+				tip:       "Let {x} suggest the adventure and {y} plan the logistics, so each plays to their strength.",
+			},
+		},
+		"decision": {
+			"F": {
+				strength:  "{a} and {b} are both attuned to feelings, which makes the friendship warm and emotionally supportive.",
+				challenge: "Both may avoid hard conversations to keep the peace, letting small hurts build up.",
+				tip:       "Make it safe to raise small issues early, before they grow into resentment.",
+			},
+			"T": {
+				strength:  "{a} and {b} can debate openly and give honest feedback without taking it personally.",
+				challenge: "Emotional support may come out as problem-solving when one of them just wants to vent.",
+				tip:       "Ask \"do you want advice or just an ear?\" before jumping into solutions.",
+			},
+			"mixed": {
+				strength:  "{x} offers clear, objective advice while {y} brings empathy and reads the emotional undercurrent.",
+				challenge: "{x}'s bluntness can sting {y}, and {y}'s emphasis on feelings can seem illogical to {x}.",
+				tip:       "{x} can soften delivery with acknowledgement, and {y} can say directly what kind of support they need.",
+			},
+		},
+		"lifestyle": {
+			"J": {
+				strength:  "Plans get made and kept, so {a} and {b} can rely on each other to show up.",
+				challenge: "Two planners may clash when their schedules or preferred plans conflict.",
+				tip:       "Decide together who owns which plans so neither feels overruled.",
+			},
+			"P": {
+				strength:  "{a} and {b} are happy to go with the flow, which makes last-minute adventures easy.",
+				challenge: "Plans can fall through when nobody commits to a time or place.",
+				tip:       "Lock in at least a date for important get-togethers, even if the details stay open.",
+			},
+			"mixed": {
+				strength:  "{x} keeps things organised while {y} keeps things spontaneous and fun.",
+				challenge: "{x} may feel frustrated by {y}'s last-minute changes, and {y} may feel boxed in by {x}'s plans.",
+				tip:       "Plan the essentials and leave part of the time unscheduled for spontaneity.",
+			},
+		},
+	},
+	"workplace": {
+		"energy": {
+			"E": {
+				strength:  "{a} and {b} both think out loud and energise meetings, brainstorms and client-facing work.",
+				challenge: "Discussions can expand to fill the time, leaving less room for focused individual work.",
+				tip:       "Timebox brainstorming and end meetings with clear owners and next steps.",
+			},
+			"I": {
+				strength:  "{a} and {b} both respect focus time and prefer written, well-considered communication.",
+				challenge: "Important concerns may go unspoken because neither raises them in the moment.",
+				tip:       "Use shared documents or async check-ins so ideas and blockers surface early.",
+			},
+			"mixed": {
+				strength:  "{x} handles networking and live discussion while {y} contributes deep, focused work.",
+				challenge: "{x} may push for quick verbal decisions before {y} has had time to think.",
+				tip:       "Share agendas in advance and allow {y} to follow up in writing after meetings.",
+			},
+		},
+		"intuition": {
+			"N": {
+				strength:  "Both are strong at strategy, spotting patterns and imagining what the work could become.",
+				challenge: "Execution details and deadlines can slip while the vision keeps evolving.",
+				tip:       "Pair each strategic decision with a concrete milestone and a named owner.",
+			},
+			"S": {
+				strength:  "Both are reliable executors who value proven methods and accurate details.",
+				challenge: "They may be slow to spot when the process itself needs to change.",
+				tip:       "Schedule regular retrospectives to question assumptions and explore new approaches.",
+			},
+			"mixed": {
+				strength:  "{x} shapes the long-term direction while {y} makes sure it works in practice.",
+				challenge: "{x} may see {y} as resistant to change, and {y} may see {x} as unrealistic.",
+				tip:       "Start projects with {x} framing the goal and {y} stress-testing the plan.",
+			},
+		},
+		"decision": {
+			"T": {
+				strength:  "{a} and {b} make decisions on evidence and can challenge each other's reasoning productively.",
+				challenge: "Team morale and stakeholder feelings can be overlooked in the push for the best answer.",
+				tip:       "Add a quick people-impact check before finalising decisions.",
+			},
+			"F": {
+				strength:  "{a} and {b} build a supportive, collaborative atmosphere and are attentive to team morale.",
+				challenge: "Tough calls and critical feedback may be delayed to avoid conflict.",
+				tip:       "Agree on clear criteria up front so difficult decisions feel less personal.",
+			},
+			"mixed": {
+				strength:  "{x} brings analytical rigour while {y} keeps the team and stakeholders on board.",
+				challenge: "{x}'s direct critiques may feel harsh to {y}, and {y}'s concerns may seem soft to {x}.",
+				tip:       "Frame feedback around shared goals so logic and empathy reinforce each other.",
+			},
+		},
+		"lifestyle": {
+			"J": {
+				strength:  "Both plan ahead, meet deadlines and appreciate clear processes.",
+				challenge: "They may lock in decisions too early and struggle when priorities shift.",
+				tip:       "Build explicit review points into plans so change has a sanctioned place.",
+			},
+			"P": {
+				strength:  "Both adapt quickly and stay open to new information as projects evolve.",
+				challenge: "Deadlines can become stressful when work is left until the last minute.",
+				tip:       "Break work into small checkpoints to keep flexibility without losing momentum.",
+			},
+			"mixed": {
+				strength:  "{x} keeps projects on schedule while {y} adapts when circumstances change.",
+				challenge: "{x} may see {y} as disorganised, and {y} may find {x}'s timelines rigid.",
+				tip:       "Let {x} own the timeline and {y} own contingency planning.",
+			},
+		},
+	},
+	"romance": {
+		"energy": {
+			"E": {
+				strength:  "{a} and {b} share an active social life and enjoy going out and meeting people together.",
+				challenge: "Time alone as a couple can get crowded out by a busy social calendar.",
+				tip:       "Protect regular date nights that are just for the two of them.",
+			},
+			"I": {
+				strength:  "{a} and {b} share a love of cosy, private time and deep one-on-one conversation.",
+				challenge: "The relationship can become insular, with few outside connections to lean on.",
+				tip:       "Keep a few shared friendships active so the couple has a wider support network.",
+			},
+			"mixed": {
+				strength:  "{x} brings excitement and social connection while {y} brings depth and calm.",
+				challenge: "{x} may feel held back socially, while {y} may feel pressured to be \"on\" too often.",
+				tip:       "Agree on a social rhythm and make it normal to attend some events separately.",
+			},
+		},
+		"intuition": {
+			"N": {
+				strength:  "{a} and {b} connect through shared dreams, ideas and meaningful conversation.",
+				challenge: "Everyday practicalities like bills and chores can be neglected.",
+				tip:       "Set up simple systems for household tasks so they do not become a source of friction.",
+			},
+			"S": {
+				strength:  "{a} and {b} show love through practical care and enjoy building a stable, comfortable life.",
+				challenge: "Romance can become routine without deliberate novelty.",
+				tip:       "Plan occasional surprises or new experiences to keep the spark alive.",
+			},
+			"mixed": {
+				strength:  "{x} imagines the future together while {y} builds the practical foundation to get there.",
+				challenge: "{x} may feel {y} is not dreaming big enough, and {y} may feel {x} overlooks the here and now.",
+				tip:       "Talk about long-term goals and the next concrete step in the same conversation.",
+			},
+		},
+		"decision": {
+			"F": {
+				strength:  "{a} and {b} are emotionally expressive and attentive to each other's feelings.",
+				challenge: "Both may avoid conflict, leaving important disagreements unresolved.",
+				tip:       "Schedule calm check-ins where raising concerns is expected, not a crisis.",
+			},
+			"T": {
+				strength:  "{a} and {b} communicate directly and resolve disagreements through reasoned discussion.",
+				challenge: "Affection and emotional validation may be under-expressed.",
+				tip:       "Make appreciation explicit, even when it feels obvious.",
+			},
+			"mixed": {
+				strength:  "{x} brings steadiness and problem-solving while {y} brings warmth and emotional insight.",
+				challenge: "{y} may feel unheard when {x} jumps to solutions, and {x} may feel overwhelmed by emotional intensity.",
+				tip:       "{x} can validate feelings before problem-solving, and {y} can name what they need in the moment.",
+			},
+		},
+		"lifestyle": {
+			"J": {
+				strength:  "Both value commitment and structure, which makes shared plans and goals easy to pursue.",
+				challenge: "Disagreements about how things should be done can become rigid standoffs.",
+				tip:       "Divide decision areas so each partner has clear ownership.",
+			},
+			"P": {
+				strength:  "Both are spontaneous and open-minded, keeping the relationship playful and adaptable.",
+				challenge: "Big life decisions can be postponed indefinitely.",
+				tip:       "Set gentle deadlines for important decisions while keeping day-to-day life flexible.",
+			},
+			"mixed": {
+				strength:  "{x} provides stability and follow-through while {y} adds spontaneity and fun.",
+				challenge: "{x} may feel anxious about open-ended plans, and {y} may feel constrained by schedules.",
+				tip:       "Agree which parts of life need a plan and which can stay open.",
+			},
+		},
+	},
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// openAIProvider talks to any server implementing the OpenAI chat completions
+// API, such as OpenAI itself, Ollama, vLLM or LM Studio.
+type openAIProvider struct {
+	name    string
+	baseURL string
+	apiKey  string
+	model   string
+}
+
+// newOpenAIProviderFromEnv reads LLM_BASE_URL, LLM_MODEL and LLM_API_KEY, falling
+// back to the given defaults for the base URL and model.
+func newOpenAIProviderFromEnv(name, defaultBaseURL, defaultModel string) (*openAIProvider, error) {
+	baseURL := strings.TrimSpace(os.Getenv("LLM_BASE_URL"))
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+
+	model := strings.TrimSpace(os.Getenv("LLM_MODEL"))
+	if model == "" {
+		model = defaultModel
+	}
+
+	apiKey := os.Getenv("LLM_API_KEY")
+	if name == "openai" && apiKey == "" {
+		return nil, fmt.Errorf("LLM_API_KEY environment variable not set")
+	}
+
+	return &openAIProvider{
+		name:    name,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+	}, nil
+}
+
+func (o *openAIProvider) Name() string {
+	return o.name
+}
+
+func (o *openAIProvider) Model() string {
+	return o.model
+}
+
+func (o *openAIProvider) Generate(ctx context.Context, req GenerationRequest) (*GenerationResult, error) {
+	payload := map[string]interface{}{
+		"model": o.model,
+		"messages": []map[string]string{
+			{
+				"role":    "user",
+				"content": req.Prompt,
+			},
+		},
+	}
+	if req.MaxOutputTokens > 0 {
+		payload["max_tokens"] = req.MaxOutputTokens
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	headers := map[string]string{}
+	if o.apiKey != "" {
+		headers["Authorization"] = "Bearer " + o.apiKey
+	}
+
+	body, err := postJSON(ctx, o.baseURL+"/chat/completions", headers, nil, payloadJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	var completion struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+			TotalTokens      int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+
+	if err := json.Unmarshal(body, &completion); err != nil {
+		return nil, unparseable(fmt.Errorf("failed to parse response: %w", err))
+	}
+
+	usage := TokenUsage{
+		PromptTokens:    completion.Usage.PromptTokens,
+		CandidateTokens: completion.Usage.CompletionTokens,
+		TotalTokens:     completion.Usage.TotalTokens,
+	}
+
+	if len(completion.Choices) == 0 {
+		return nil, withUsage(unparseable(fmt.Errorf("no content in response")), usage)
+	}
+
+	choice := completion.Choices[0]
+	if choice.FinishReason == "content_filter" {
+		return nil, withUsage(safetyBlocked(choice.FinishReason, nil), usage)
+	}
+
+	return &GenerationResult{
+		Text:         choice.Message.Content,
+		FinishReason: openAIFinishReason(choice.FinishReason),
+		Usage:        usage,
+	}, nil
+}
+
+// openAIFinishReason maps an OpenAI finish_reason to the Gemini names used in GenerationResult
+func openAIFinishReason(reason string) string {
+	switch reason {
+	case "":
+		return ""
+	case "stop":
+		return finishStop
+	case "length":
+		return finishMaxTokens
+	default:
+		return strings.ToUpper(reason)
+	}
+}
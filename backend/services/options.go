@@ -0,0 +1,141 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Tones of the generated explanations
+const (
+	TonePlayful      = "playful"
+	ToneNeutral      = "neutral"
+	ToneProfessional = "professional"
+)
+
+// Depths of the generated explanations
+const (
+	DepthSummary  = "summary"
+	DepthStandard = "standard"
+	DepthDeepDive = "deep-dive"
+)
+
+var (
+	tones  = []string{TonePlayful, ToneNeutral, ToneProfessional}
+	depths = []string{DepthSummary, DepthStandard, DepthDeepDive}
+)
+
+// depthTokenScale scales LLM_MAX_OUTPUT_TOKENS for each depth
+var depthTokenScale = map[string]float64{
+	DepthSummary:  0.5,
+	DepthStandard: 1,
+	DepthDeepDive: 2,
+}
+
+// depthOutputTokens is the output budget per category for each depth when
+// LLM_MAX_OUTPUT_TOKENS is unset
+var depthOutputTokens = map[string]int{
+	DepthSummary:  1024,
+	DepthStandard: 2048,
+	DepthDeepDive: 4096,
+}
+
+// AssessmentOptions tunes how an assessment is generated. The zero value uses
+// the server defaults.
+type AssessmentOptions struct {
+	// Samples is how many independent model samples are combined into one
+	// consensus result; zero uses LLM_SAMPLES
+	Samples int
+	// Language is a BCP-47 tag for the language of the explanations; empty
+	// uses English
+	Language string
+	// Tone is playful, neutral or professional; empty uses neutral
+	Tone string
+	// Depth is summary, standard or deep-dive; empty uses standard
+	Depth string
+}
+
+// NormalizeOptions validates options and fills in the server defaults, so
+// callers can record the options an assessment was generated with
+func NormalizeOptions(options AssessmentOptions) (AssessmentOptions, error) {
+	if err := options.validate(); err != nil {
+		return options, err
+	}
+	return options.withDefaults(), nil
+}
+
+// withDefaults fills unset options from the environment
+func (o AssessmentOptions) withDefaults() AssessmentOptions {
+	if o.Samples == 0 {
+		o.Samples = envInt("LLM_SAMPLES", 1)
+	}
+	if o.Samples < 1 {
+		o.Samples = 1
+	}
+	if language, err := NormalizeLanguage(o.Language); err == nil {
+		o.Language = language
+	}
+	o.Tone = strings.ToLower(strings.TrimSpace(o.Tone))
+	if o.Tone == "" {
+		o.Tone = ToneNeutral
+	}
+	o.Depth = strings.ToLower(strings.TrimSpace(o.Depth))
+	if o.Depth == "" {
+		o.Depth = DepthStandard
+	}
+	return o
+}
+
+// validate rejects options the server will not honor
+func (o AssessmentOptions) validate() error {
+	if limit := envInt("LLM_MAX_SAMPLES", 5); o.Samples < 0 || o.Samples > limit {
+		return newError(ErrInvalidInput, fmt.Sprintf("Samples must be between 1 and %d", limit), nil)
+	}
+	if _, err := NormalizeLanguage(o.Language); err != nil {
+		return err
+	}
+	if tone := strings.ToLower(strings.TrimSpace(o.Tone)); tone != "" && !contains(tones, tone) {
+		return newError(ErrInvalidInput, "Tone must be one of "+strings.Join(tones, ", "), nil)
+	}
+	if depth := strings.ToLower(strings.TrimSpace(o.Depth)); depth != "" && !contains(depths, depth) {
+		return newError(ErrInvalidInput, "Depth must be one of "+strings.Join(depths, ", "), nil)
+	}
+	return nil
+}
+
+// key identifies the options in coalescing keys
+func (o AssessmentOptions) key() string {
+	return "samples=" + strconv.Itoa(o.Samples) + ",language=" + o.Language + ",tone=" + o.Tone + ",depth=" + o.Depth
+}
+
+// maxOutputTokens is the output budget for a generation covering the given
+// number of categories: per category, LLM_MAX_OUTPUT_TOKENS scaled by the depth,
+// or the depth's default budget when it is unset. The total is capped at
+// LLM_MAX_OUTPUT_TOKENS_LIMIT.
+func (o AssessmentOptions) maxOutputTokens(categories int) int {
+	limit := envInt("LLM_MAX_OUTPUT_TOKENS_LIMIT", 8192)
+
+	base := envInt("LLM_MAX_OUTPUT_TOKENS", 0)
+	if base <= 0 {
+		perCategory, ok := depthOutputTokens[o.Depth]
+		if !ok {
+			perCategory = depthOutputTokens[DepthStandard]
+		}
+		return min(perCategory*categories, limit)
+	}
+
+	scale, ok := depthTokenScale[o.Depth]
+	if !ok {
+		scale = 1
+	}
+	return min(int(float64(base)*scale)*categories, limit)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"bytes"
+	"compatiblah/backend/models"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// Prompt template names, one file per name in services/prompts
+const (
+	promptAssessment = "assessment"
+	promptCategory   = "category"
+)
+
+var promptNames = []string{promptAssessment, promptCategory}
+
+//go:embed prompts/*.tmpl
+var embeddedPrompts embed.FS
+
+// promptVersionHeader must open every template, e.g. {{/* version: v3 */ -}}.
+// Bump the version whenever the wording changes so cached responses from the old
+// prompt are not reused and assessments can be traced back to the prompt that made them.
+var promptVersionHeader = regexp.MustCompile(`^\{\{-?\s*/\*\s*version:\s*(\S+)\s*\*/\s*-?\}\}`)
+
+// promptTemplate is a parsed prompt template and its declared version
+type promptTemplate struct {
+	tmpl *template.Template
+	// version is "<name>/<declared version>", e.g. "category/v1"
+	version string
+}
+
+// promptData is the data passed to every prompt template
+type promptData struct {
+	Person1  models.PersonData
+	Person2  models.PersonData
+	Category string
+	// Base is the indented JSON of an explanation to augment, empty if none
+	Base string
+	// Context is additional user-supplied context for a refinement, empty if none
+	Context string
+	// Language is the name of the language to write in, empty for English
+	Language string
+	// Tone is "playful", "neutral" or "professional"
+	Tone string
+	// Depth is "summary", "standard" or "deep-dive"
+	Depth string
+}
+
+// promptFuncs are available to every prompt template
+var promptFuncs = template.FuncMap{
+	// quote renders user-supplied text as a JSON string so it reads as data, not instructions
+	"quote": quotePromptValue,
+}
+
+func quotePromptValue(value string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return `""`
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+var (
+	promptsMu sync.RWMutex
+	prompts   = mustLoadPrompts(embeddedPrompts, "prompts")
+)
+
+// LoadPromptsFromEnv replaces the embedded prompt templates with files from
+// PROMPT_DIR. Templates missing from the directory keep their embedded version.
+func LoadPromptsFromEnv() error {
+	dir := strings.TrimSpace(os.Getenv("PROMPT_DIR"))
+	if dir == "" {
+		return nil
+	}
+
+	loaded, err := loadPrompts(overlayFS{primary: os.DirFS(dir), fallback: embeddedPrompts, fallbackDir: "prompts"}, ".")
+	if err != nil {
+		return fmt.Errorf("failed to load prompts from %s: %w", dir, err)
+	}
+
+	promptsMu.Lock()
+	prompts = loaded
+	promptsMu.Unlock()
+
+	for _, name := range promptNames {
+		log.Printf("Using prompt %s", loaded[name].version)
+	}
+	return nil
+}
+
+func mustLoadPrompts(fsys fs.FS, dir string) map[string]*promptTemplate {
+	loaded, err := loadPrompts(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return loaded
+}
+
+// loadPrompts parses every named template from dir in fsys
+func loadPrompts(fsys fs.FS, dir string) (map[string]*promptTemplate, error) {
+	loaded := make(map[string]*promptTemplate, len(promptNames))
+	for _, name := range promptNames {
+		file := name + ".tmpl"
+		raw, err := fs.ReadFile(fsys, filepath.ToSlash(filepath.Join(dir, file)))
+		if err != nil {
+			return nil, err
+		}
+
+		match := promptVersionHeader.FindSubmatch(raw)
+		if match == nil {
+			return nil, fmt.Errorf("%s: missing {{/* version: ... */}} header", file)
+		}
+
+		tmpl, err := template.New(file).Funcs(promptFuncs).Parse(string(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+
+		loaded[name] = &promptTemplate{tmpl: tmpl, version: name + "/" + string(match[1])}
+	}
+	return loaded, nil
+}
+
+// overlayFS reads from primary and falls back to fallbackDir in fallback
+type overlayFS struct {
+	primary     fs.FS
+	fallback    fs.FS
+	fallbackDir string
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	file, err := o.primary.Open(name)
+	if err == nil {
+		return file, nil
+	}
+	return o.fallback.Open(filepath.ToSlash(filepath.Join(o.fallbackDir, name)))
+}
+
+func promptFor(name string) *promptTemplate {
+	promptsMu.RLock()
+	defer promptsMu.RUnlock()
+	return prompts[name]
+}
+
+// promptVersion returns the version of the named prompt template. Templates are
+// only replaced at startup, so this matches the template used for any prompt.
+func promptVersion(name string) string {
+	return promptFor(name).version
+}
+
+// promptNameFor returns the template used for a cache category ("all" or a category name)
+func promptNameFor(category string) string {
+	if category == "all" {
+		return promptAssessment
+	}
+	return promptCategory
+}
+
+// renderPrompt executes the named template
+func renderPrompt(name string, data promptData) (string, error) {
+	var buf bytes.Buffer
+	if err := promptFor(name).tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %s prompt: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// buildPrompt renders the full three-category assessment prompt
+func buildPrompt(person1, person2 models.PersonData, options AssessmentOptions) (string, error) {
+	return renderPrompt(promptAssessment, promptData{
+		Person1:  person1,
+		Person2:  person2,
+		Language: promptLanguage(options.Language),
+		Tone:     options.Tone,
+		Depth:    options.Depth,
+	})
+}
+
+// buildCategoryPrompt renders the prompt for a single compatibility category
+func buildCategoryPrompt(person1, person2 models.PersonData, category string, options AssessmentOptions) (string, error) {
+	return buildCategoryPromptWithBase(person1, person2, category, nil, "", options)
+}
+
+// buildCategoryPromptWithBase renders a single-category prompt that augments
+// baseExplanation, optionally with additional context from the user
+func buildCategoryPromptWithBase(person1, person2 models.PersonData, category string, baseExplanation *models.CategoryExplanation, additionalContext string, options AssessmentOptions) (string, error) {
+	data := promptData{
+		Person1:  person1,
+		Person2:  person2,
+		Category: category,
+		Context:  additionalContext,
+		Language: promptLanguage(options.Language),
+		Tone:     options.Tone,
+		Depth:    options.Depth,
+	}
+
+	if baseExplanation != nil {
+		baseJSON, err := json.MarshalIndent(baseExplanation, "", "  ")
+		if err == nil {
+			data.Base = string(baseJSON)
+		}
+	}
+
+	return renderPrompt(promptCategory, data)
+}
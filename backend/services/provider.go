@@ -0,0 +1,411 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LLMProvider generates text for assessment prompts. Gemini is the default
+// implementation; OpenAI-compatible servers (including Ollama) are also supported.
+type LLMProvider interface {
+	// Name identifies the provider implementation, e.g. "gemini" or "openai".
+	Name() string
+	// Model returns the model identifier requests are sent to.
+	Model() string
+	// Generate sends the request to the model and returns its text output.
+	// Implementations must stop work when ctx is cancelled.
+	Generate(ctx context.Context, req GenerationRequest) (*GenerationResult, error)
+}
+
+// StreamingProvider is implemented by providers that can return output
+// incrementally. Providers without it are still usable for streamed
+// assessments; their output arrives as a single chunk.
+type StreamingProvider interface {
+	LLMProvider
+	// GenerateStream behaves like Generate but calls onChunk with each piece of
+	// text as it arrives. The returned result holds the full text.
+	GenerateStream(ctx context.Context, req GenerationRequest, onChunk func(string)) (*GenerationResult, error)
+}
+
+// GenerationRequest describes a single prompt sent to an LLMProvider
+type GenerationRequest struct {
+	Prompt string
+	// Schema optionally constrains the output to JSON matching this schema.
+	// Providers without structured output support ignore it.
+	Schema map[string]interface{}
+	// Category labels the call for usage accounting: "friend", "coworker",
+	// "partner" or "all" for a full assessment.
+	Category string
+	// MaxOutputTokens caps the response length; zero uses the provider default
+	MaxOutputTokens int
+}
+
+// GenerationResult holds the text produced by an LLMProvider
+type GenerationResult struct {
+	Text string
+	// Structured is true when the provider enforced the request schema, so
+	// Text can be decoded strictly without cleanup heuristics.
+	Structured bool
+	// Usage is the token count reported by the provider, zero if unreported
+	Usage TokenUsage
+	// FinishReason is why the model stopped, normalized to Gemini's names
+	// ("STOP", "MAX_TOKENS", ...); empty if the provider did not say
+	FinishReason string
+	// SafetyRatings are the provider's content safety assessments, if any
+	SafetyRatings []SafetyRating
+}
+
+// SafetyRating is a provider's assessment of one harm category
+type SafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked,omitempty"`
+}
+
+// Finish reasons that need handling beyond a normal stop
+const (
+	finishStop      = "STOP"
+	finishMaxTokens = "MAX_TOKENS"
+)
+
+// maxTokenRetries bounds how often output cut off at the token limit is regenerated
+const maxTokenRetries = 2
+
+// conciseInstruction is appended to prompts whose output was cut off once the
+// output budget cannot grow any further
+const conciseInstruction = "\n\nIMPORTANT: A previous answer to this request was cut off because it was too long. Keep every bullet point to a single sentence so the complete JSON object fits."
+
+var (
+	providerMu     sync.RWMutex
+	activeProvider LLMProvider
+)
+
+// SetProvider sets the provider used by the assessment functions, guarded by a
+// circuit breaker so repeated upstream failures switch to heuristic-only results
+func SetProvider(p LLMProvider) {
+	providerMu.Lock()
+	defer providerMu.Unlock()
+
+	if p == nil {
+		activeProvider = nil
+		return
+	}
+	activeProvider = &breakerProvider{LLMProvider: p, breaker: newCircuitBreakerFromEnv()}
+}
+
+// ErrNoProvider is returned when the service runs in offline mode without an LLM
+var ErrNoProvider = errors.New("no LLM provider configured")
+
+func currentProvider() (LLMProvider, error) {
+	providerMu.RLock()
+	defer providerMu.RUnlock()
+	if activeProvider == nil {
+		return nil, ErrNoProvider
+	}
+	return activeProvider, nil
+}
+
+// NewProviderFromEnv builds the provider selected by the LLM_PROVIDER environment
+// variable: "gemini" (default), "openai", "ollama" or "offline". It returns a nil
+// provider for offline mode, which is also used when LLM_PROVIDER is unset and
+// no Gemini API key is configured.
+func NewProviderFromEnv() (LLMProvider, error) {
+	name := strings.ToLower(strings.TrimSpace(os.Getenv("LLM_PROVIDER")))
+
+	switch name {
+	case "":
+		if !geminiKeysConfigured() {
+			return nil, nil
+		}
+		return newGeminiProviderFromEnv()
+	case "offline":
+		return nil, nil
+	case "gemini":
+		return newGeminiProviderFromEnv()
+	case "openai":
+		return newOpenAIProviderFromEnv("openai", "https://api.openai.com/v1", "gpt-4o-mini")
+	case "ollama":
+		return newOpenAIProviderFromEnv("ollama", "http://localhost:11434/v1", "llama3.1")
+	default:
+		return nil, fmt.Errorf("unknown LLM_PROVIDER %q (expected gemini, openai, ollama or offline)", name)
+	}
+}
+
+// httpClient is shared by all providers; deadlines come from the request context
+var httpClient = &http.Client{}
+
+// requestTimeout bounds a whole generation including retries (LLM_REQUEST_TIMEOUT)
+func requestTimeout() time.Duration {
+	return envDuration("LLM_REQUEST_TIMEOUT", 90*time.Second)
+}
+
+// attemptTimeout bounds a single upstream HTTP attempt (LLM_ATTEMPT_TIMEOUT)
+func attemptTimeout() time.Duration {
+	return envDuration("LLM_ATTEMPT_TIMEOUT", 45*time.Second)
+}
+
+// generate sends the request to the active provider and returns the raw model output
+func generate(ctx context.Context, req GenerationRequest) (*GenerationResult, error) {
+	return runGeneration(ctx, req, func(ctx context.Context, provider LLMProvider, req GenerationRequest) (*GenerationResult, error) {
+		return provider.Generate(ctx, req)
+	}, nil)
+}
+
+// generateStream is like generate but reports output chunks to onChunk as they
+// arrive. Output cut off at the token limit is only regenerated if no chunk was
+// delivered, so the caller never sees parts of two different generations.
+func generateStream(ctx context.Context, req GenerationRequest, onChunk func(string)) (*GenerationResult, error) {
+	delivered := false
+	return runGeneration(ctx, req, func(ctx context.Context, provider LLMProvider, req GenerationRequest) (*GenerationResult, error) {
+		return streamFrom(ctx, provider, req, func(chunk string) {
+			delivered = true
+			onChunk(chunk)
+		})
+	}, func() bool { return !delivered })
+}
+
+// runGeneration performs call against the active provider under the request
+// timeout, records usage and regenerates output that was cut off at the token
+// limit while canRetry (nil means always) allows it
+func runGeneration(ctx context.Context, req GenerationRequest, call func(context.Context, LLMProvider, GenerationRequest) (*GenerationResult, error), canRetry func() bool) (*GenerationResult, error) {
+	provider, err := currentProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	if timeout := requestTimeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if req.MaxOutputTokens == 0 {
+		req.MaxOutputTokens = envInt("LLM_MAX_OUTPUT_TOKENS", 0)
+	}
+
+	result, err := call(ctx, provider, req)
+	if err != nil {
+		recordFailedUsage(ctx, provider, req, err)
+		return nil, err
+	}
+	recordUsage(ctx, provider, req, result.Usage)
+	noteFinishReason(result)
+
+	// Retries are not streamed, so they only run before any chunk was delivered
+	retryable := canRetry == nil || canRetry()
+	for retry := 1; retryable && result.FinishReason == finishMaxTokens && retry <= maxTokenRetries; retry++ {
+		req = truncationRetry(req)
+		incMetric("max_tokens_retries")
+		log.Printf("Model output hit the token limit, retrying (%d/%d) with max_output_tokens=%d", retry, maxTokenRetries, req.MaxOutputTokens)
+
+		result, err = provider.Generate(ctx, req)
+		if err != nil {
+			recordFailedUsage(ctx, provider, req, err)
+			return nil, err
+		}
+		recordUsage(ctx, provider, req, result.Usage)
+		noteFinishReason(result)
+	}
+
+	if result.FinishReason == finishMaxTokens {
+		return nil, unparseable(fmt.Errorf("model output was cut off at the token limit"))
+	}
+
+	if strings.TrimSpace(result.Text) == "" {
+		return nil, unparseable(fmt.Errorf("no content in response"))
+	}
+
+	return result, nil
+}
+
+// truncationRetry returns the request to send after output was cut off: a
+// doubled output budget while it is below LLM_MAX_OUTPUT_TOKENS_LIMIT, then the
+// same budget with an instruction to answer more concisely
+func truncationRetry(req GenerationRequest) GenerationRequest {
+	limit := envInt("LLM_MAX_OUTPUT_TOKENS_LIMIT", 8192)
+	if req.MaxOutputTokens > 0 && req.MaxOutputTokens < limit {
+		req.MaxOutputTokens = min(req.MaxOutputTokens*2, limit)
+		return req
+	}
+
+	if !strings.HasSuffix(req.Prompt, conciseInstruction) {
+		req.Prompt += conciseInstruction
+	}
+	return req
+}
+
+// noteFinishReason counts and logs generations that did not stop normally
+func noteFinishReason(result *GenerationResult) {
+	if result.FinishReason == "" || result.FinishReason == finishStop {
+		return
+	}
+	incMetric("finish_reason_" + strings.ToLower(result.FinishReason))
+	log.Printf("Model finished with reason %s", result.FinishReason)
+}
+
+// streamFrom streams from provider when it supports it, otherwise it delivers the
+// whole output as one chunk
+func streamFrom(ctx context.Context, provider LLMProvider, req GenerationRequest, onChunk func(string)) (*GenerationResult, error) {
+	if streaming, ok := provider.(StreamingProvider); ok {
+		return streaming.GenerateStream(ctx, req, onChunk)
+	}
+
+	result, err := provider.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	onChunk(result.Text)
+	return result, nil
+}
+
+// postJSON sends a JSON payload, retrying transient failures according to the
+// retry policy. Each attempt gets its own deadline and backoff stops as soon as
+// ctx is done.
+func postJSON(ctx context.Context, url string, headers map[string]string, keys *keyPool, payloadJSON []byte) ([]byte, error) {
+	var body []byte
+	err := withRetry(ctx, func() error {
+		attemptHeaders, report, err := keys.headers(headers)
+		if err != nil {
+			return err
+		}
+
+		respBody, statusCode, header, err := postJSONOnce(ctx, url, attemptHeaders, payloadJSON)
+		if err != nil {
+			return transportFailure(ctx, err)
+		}
+
+		if statusCode != http.StatusOK {
+			return keyedStatusFailure(statusCode, header, respBody, report)
+		}
+
+		body = respBody
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// postJSONOnce performs a single POST bounded by the per-attempt timeout
+func postJSONOnce(ctx context.Context, url string, headers map[string]string, payloadJSON []byte) ([]byte, int, http.Header, error) {
+	if timeout := attemptTimeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	resp, err := sendJSON(ctx, url, headers, payloadJSON)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return body, resp.StatusCode, resp.Header, nil
+}
+
+// postJSONStream sends a JSON payload and passes each line of a successful
+// response body to onLine as it is received. Failures before streaming starts
+// are retried like postJSON; once lines have been delivered nothing is retried.
+// No per-attempt timeout is applied because a stream legitimately stays open
+// while the model is writing.
+func postJSONStream(ctx context.Context, url string, headers map[string]string, keys *keyPool, payloadJSON []byte, onLine func([]byte) error) error {
+	return withRetry(ctx, func() error {
+		attemptHeaders, report, err := keys.headers(headers)
+		if err != nil {
+			return err
+		}
+
+		resp, err := sendJSON(ctx, url, attemptHeaders, payloadJSON)
+		if err != nil {
+			return transportFailure(ctx, err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			return keyedStatusFailure(resp.StatusCode, resp.Header, body, report)
+		}
+
+		return scanLines(resp.Body, onLine)
+	})
+}
+
+// keyedStatusFailure is statusFailure for a request made with a pooled key. When
+// report quarantined the key and another is available, the attempt is retried
+// straight away with that key instead of honoring the server's delay.
+func keyedStatusFailure(statusCode int, header http.Header, body []byte, report func(int, http.Header, []byte) bool) error {
+	err := statusFailure(statusCode, header, body)
+	if !report(statusCode, header, body) {
+		return err
+	}
+
+	var transient *transientError
+	if errors.As(err, &transient) {
+		err = transient.err
+	}
+	return &transientError{err: err, after: time.Nanosecond}
+}
+
+// sendJSON issues a POST with a JSON body; the caller must close the response body
+func sendJSON(ctx context.Context, url string, headers map[string]string, payloadJSON []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(payloadJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	return resp, nil
+}
+
+// scanLines calls onLine for every line in r, stopping at the first error
+func scanLines(r io.Reader, onLine func([]byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		if err := onLine(scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read response stream: %w", err)
+	}
+	return nil
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// retryPolicy controls how failed upstream calls are retried
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+// retryPolicyFromEnv reads LLM_RETRY_MAX_ATTEMPTS, LLM_RETRY_BASE_DELAY and LLM_RETRY_MAX_DELAY
+func retryPolicyFromEnv() retryPolicy {
+	attempts := envInt("LLM_RETRY_MAX_ATTEMPTS", 3)
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	base := envDuration("LLM_RETRY_BASE_DELAY", time.Second)
+	maxDelay := envDuration("LLM_RETRY_MAX_DELAY", 20*time.Second)
+	if maxDelay < base {
+		maxDelay = base
+	}
+
+	return retryPolicy{maxAttempts: attempts, baseDelay: base, maxDelay: maxDelay}
+}
+
+// backoff returns a full-jitter delay after the given failed attempt: a random
+// duration between zero and min(maxDelay, baseDelay * 2^(attempt-1))
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	ceiling := p.maxDelay
+	if attempt-1 < 31 {
+		if exp := p.baseDelay << uint(attempt-1); exp > 0 && exp < ceiling {
+			ceiling = exp
+		}
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// transientError marks an attempt failure that may succeed if repeated. after
+// is the delay the server asked for, or zero to use the policy backoff.
+type transientError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *transientError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transientError) Unwrap() error {
+	return e.err
+}
+
+// withRetry runs attempt until it succeeds, fails with a non-transient error or
+// the policy gives up. Generation requests have no side effects, so repeating
+// them is safe.
+func withRetry(ctx context.Context, attempt func() error) error {
+	policy := retryPolicyFromEnv()
+
+	for n := 1; ; n++ {
+		incMetric("upstream_attempts")
+		err := attempt()
+
+		var transient *transientError
+		if !errors.As(err, &transient) {
+			if err == nil && n > 1 {
+				log.Printf("Upstream call succeeded after %d attempts", n)
+			}
+			return err
+		}
+
+		if n >= policy.maxAttempts {
+			incMetric("upstream_retries_exhausted")
+			log.Printf("Upstream call failed after %d attempts: %v", n, transient.err)
+			return transient.err
+		}
+
+		wait := transient.after
+		if wait > policy.maxDelay {
+			// The server wants us to back off longer than we are willing to wait
+			incMetric("upstream_retries_exhausted")
+			log.Printf("Upstream call failed on attempt %d, server asked to retry after %s: %v", n, wait, transient.err)
+			return transient.err
+		}
+		if wait <= 0 {
+			wait = policy.backoff(n)
+		}
+
+		incMetric("upstream_retries")
+		log.Printf("Upstream call failed (attempt %d/%d), retrying in %s: %v", n, policy.maxAttempts, wait.Round(time.Millisecond), transient.err)
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// transportFailure classifies an error from sending a request. Anything other
+// than the caller's own cancellation (connection resets, DNS hiccups, the
+// per-attempt timeout) is treated as transient.
+func transportFailure(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return err
+	}
+	return &transientError{err: upstreamUnreachable(err)}
+}
+
+// statusFailure builds the error for a non-200 response, marking 429 and 5xx
+// gateway/availability errors as transient
+func statusFailure(statusCode int, header http.Header, body []byte) error {
+	err := upstreamStatusError(statusCode, body)
+
+	switch statusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return &transientError{err: err, after: serverRetryDelay(header, body)}
+	default:
+		return err
+	}
+}
+
+// serverRetryDelay returns the delay requested by the Retry-After header or, for
+// Gemini, the google.rpc.RetryInfo error detail. Zero means no preference.
+func serverRetryDelay(header http.Header, body []byte) time.Duration {
+	if raw := strings.TrimSpace(header.Get("Retry-After")); raw != "" {
+		if seconds, err := strconv.Atoi(raw); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		if at, err := http.ParseTime(raw); err == nil {
+			if wait := time.Until(at); wait > 0 {
+				return wait
+			}
+			return 0
+		}
+	}
+
+	var apiErr struct {
+		Error struct {
+			Details []struct {
+				Type       string `json:"@type"`
+				RetryDelay string `json:"retryDelay"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &apiErr) != nil {
+		return 0
+	}
+
+	for _, detail := range apiErr.Error.Details {
+		if !strings.HasSuffix(detail.Type, "google.rpc.RetryInfo") {
+			continue
+		}
+		if wait, err := time.ParseDuration(detail.RetryDelay); err == nil && wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
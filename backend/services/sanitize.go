@@ -0,0 +1,171 @@
+package services
+
+import (
+	"compatiblah/backend/models"
+	"fmt"
+	"golang.org/x/text/unicode/norm"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// injectionPatterns catch free text, such as model output, refinement
+// context or names, that repeats or gives instructions to the model. They only
+// match whole instruction phrases so ordinary words such as "score" or "rules"
+// are allowed; prompts quote user data and the output check covers the rest.
+var injectionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(ignore|disregard|forget)\s+(all\s+|any\s+|the\s+)?(previous|prior|above|earlier)\s+(instructions|prompts?)\b`),
+	regexp.MustCompile(`(?i)\b(system prompt|jailbreak|as an ai language model)\b`),
+}
+
+// NormalizePerson cleans up a person's name and MBTI type before they are used
+// in a prompt. person is 1 or 2 and only used in error messages.
+func NormalizePerson(data models.PersonData, person int) (models.PersonData, error) {
+	name, err := normalizeName(data.Name)
+	if err != nil {
+		incMetric("input_rejected")
+		return data, newError(ErrInvalidInput, fmt.Sprintf("Person %d's %s", person, err.Error()), err)
+	}
+
+	mbti := strings.ToUpper(strings.TrimSpace(data.MBTI))
+	if name == "" || mbti == "" {
+		return data, newError(ErrInvalidInput, fmt.Sprintf("Person %d must have a name and MBTI type", person), nil)
+	}
+
+	return models.PersonData{Name: name, MBTI: mbti}, nil
+}
+
+// normalizeName applies NFKC normalization, collapses whitespace and enforces NAME_MAX_LENGTH and the allowed character set. An empty result is
+// left for the caller to reject.
+func normalizeName(raw string) (string, error) {
+	name := collapseSpaces(norm.NFKC.String(raw))
+
+	if limit := envInt("NAME_MAX_LENGTH", 40); len([]rune(name)) > limit {
+		return "", fmt.Errorf("name must be at most %d characters", limit)
+	}
+
+	for _, r := range name {
+		if !nameRuneAllowed(r) {
+			return "", fmt.Errorf("name may only contain letters, numbers, spaces, apostrophes, hyphens and periods")
+		}
+	}
+
+	for _, pattern := range injectionPatterns {
+		if pattern.MatchString(name) {
+			return "", fmt.Errorf("name does not look like a name")
+		}
+	}
+
+	return name, nil
+}
+
+// NormalizeContext cleans up free-text context supplied with a refinement,
+// keeping line breaks, and enforces CONTEXT_MAX_LENGTH
+func NormalizeContext(raw string) (string, error) {
+	var lines []string
+	for _, line := range strings.Split(norm.NFKC.String(raw), "\n") {
+		if line := collapseSpaces(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	text := strings.Join(lines, "\n")
+
+	if limit := envInt("CONTEXT_MAX_LENGTH", 500); len([]rune(text)) > limit {
+		incMetric("input_rejected")
+		return "", newError(ErrInvalidInput, fmt.Sprintf("Context must be at most %d characters", limit), nil)
+	}
+
+	for _, pattern := range injectionPatterns {
+		if match := pattern.FindString(text); match != "" {
+			incMetric("input_rejected")
+			return "", newError(ErrInvalidInput, "Context must describe the people, not give instructions", fmt.Errorf("context matched %q", match))
+		}
+	}
+
+	return text, nil
+}
+
+// collapseSpaces drops control and formatting characters (including zero-width
+// and bidi overrides) and collapses runs of whitespace into single spaces
+func collapseSpaces(text string) string {
+	var cleaned strings.Builder
+	for _, r := range text {
+		switch {
+		case unicode.IsSpace(r):
+			cleaned.WriteRune(' ')
+		case unicode.IsControl(r), unicode.Is(unicode.Cf, r):
+			continue
+		default:
+			cleaned.WriteRune(r)
+		}
+	}
+	return strings.Join(strings.Fields(cleaned.String()), " ")
+}
+
+func nameRuneAllowed(r rune) bool {
+	if unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsDigit(r) {
+		return true
+	}
+	switch r {
+	case ' ', '\'', '’', '-', '.':
+		return true
+	}
+	return false
+}
+
+// checkAssessmentOutput verifies that the explanations of a decoded full
+// assessment stay within the response schema and do not echo injected
+// instructions. Out-of-range scores are already replaced by validModelScore.
+func checkAssessmentOutput(result *models.GeminiResponse) error {
+	for _, explanation := range []models.CategoryExplanation{result.FriendExplanation, result.CoworkerExplanation, result.PartnerExplanation} {
+		if err := checkExplanationOutput(explanation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCategoryOutput is checkAssessmentOutput for a single category
+func checkCategoryOutput(payload *categoryPayload) error {
+	return checkExplanationOutput(payload.Explanation)
+}
+
+// Bounds on explanation size; the prompts ask for far less
+const (
+	maxOutputSections = 10
+	maxOutputText     = 2000
+)
+
+func checkExplanationOutput(explanation models.CategoryExplanation) error {
+	if len(explanation.Sections) > maxOutputSections {
+		return rejectedOutput(fmt.Errorf("%d sections, at most %d allowed", len(explanation.Sections), maxOutputSections))
+	}
+
+	for _, section := range explanation.Sections {
+		texts := []string{section.Heading}
+		for _, subcategory := range section.Subcategories {
+			texts = append(texts, subcategory.Title)
+			for _, bullet := range subcategory.Bullets {
+				texts = append(texts, bullet.Text)
+			}
+		}
+
+		for _, text := range texts {
+			if len([]rune(text)) > maxOutputText {
+				return rejectedOutput(fmt.Errorf("text of %d characters, at most %d allowed", len([]rune(text)), maxOutputText))
+			}
+			for _, pattern := range injectionPatterns {
+				if match := pattern.FindString(text); match != "" {
+					return rejectedOutput(fmt.Errorf("output echoes instructions: %q", match))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// rejectedOutput reports model output that failed the post-generation check
+func rejectedOutput(err error) error {
+	incMetric("output_rejected")
+	return unparseable(fmt.Errorf("output failed validation: %w", err))
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"compatiblah/backend/models"
+	"reflect"
+	"strings"
+)
+
+// Response schemas sent to Gemini as generationConfig.responseSchema. They are
+// derived from the Go types the responses are decoded into so the two cannot drift.
+var (
+	assessmentSchema = schemaFor(reflect.TypeOf(models.GeminiResponse{}))
+	categorySchema   = schemaFor(reflect.TypeOf(categoryPayload{}))
+)
+
+// structuredOutputEnabled reports whether schema-constrained output should be
+// requested. It is on by default and disabled with GEMINI_STRUCTURED_OUTPUT=false.
+func structuredOutputEnabled() bool {
+	return envBool("GEMINI_STRUCTURED_OUTPUT", true)
+}
+
+// structuredSchema returns the schema when structured output is enabled, nil otherwise
+func structuredSchema(schema map[string]interface{}) map[string]interface{} {
+	if !structuredOutputEnabled() {
+		return nil
+	}
+	return schema
+}
+
+// schemaFor builds a Gemini (OpenAPI subset) schema from a Go type using its
+// json tags. Every exported field becomes a required property, in declaration order.
+func schemaFor(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "STRING"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "BOOLEAN"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "INTEGER"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "NUMBER"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  "ARRAY",
+			"items": schemaFor(t.Elem()),
+		}
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		names := []string{}
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			name := jsonFieldName(field)
+			if name == "" {
+				continue
+			}
+
+			properties[name] = schemaFor(field.Type)
+			names = append(names, name)
+		}
+
+		return map[string]interface{}{
+			"type":             "OBJECT",
+			"properties":       properties,
+			"required":         names,
+			"propertyOrdering": names,
+		}
+	default:
+		return map[string]interface{}{"type": "STRING"}
+	}
+}
+
+// jsonFieldName returns the JSON property name for a struct field, or "" if the field is skipped
+func jsonFieldName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+
+	name := strings.Split(tag, ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
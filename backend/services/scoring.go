@@ -2,10 +2,13 @@ package services
 
 import (
 	"compatiblah/backend/models"
+	"fmt"
+	"hash/fnv"
+	"log"
 	"math"
 	"math/rand"
+	"os"
 	"strings"
-	"time"
 )
 
 type compatibilityScoreSet struct {
@@ -14,45 +17,131 @@ type compatibilityScoreSet struct {
 	Partner  int
 }
 
-func calculateCompatibilityScores(person1, person2 models.PersonData) compatibilityScoreSet {
-	profile1, ok1 := parseMBTIProfile(person1.MBTI)
-	profile2, ok2 := parseMBTIProfile(person2.MBTI)
+// neutralScore is the heuristic score before any adjustment
+const neutralScore = 3.0
 
-	seed := time.Now().UnixNano()
-
-	if !ok1 || !ok2 {
-		// fallback to neutral scores with slight variation
-		return compatibilityScoreSet{
-			Friend:   clampScore(applyNoise(3.0, seed+1)),
-			Coworker: clampScore(applyNoise(3.0, seed+2)),
-			Partner:  clampScore(applyNoise(3.0, seed+3)),
-		}
+// calculateCompatibilityScores scores every category and explains each score
+func calculateCompatibilityScores(weights *scoringWeights, person1, person2 models.PersonData) (compatibilityScoreSet, models.ScoreBreakdown) {
+	breakdown := make(models.ScoreBreakdown, len(scoreCategories))
+	for _, category := range scoreCategories {
+		breakdown[category] = calculateCategoryDetails(weights, person1, person2, category)
 	}
 
 	return compatibilityScoreSet{
-		Friend:   computeCategoryScore(profile1, profile2, "friend", seed+1),
-		Coworker: computeCategoryScore(profile1, profile2, "coworker", seed+2),
-		Partner:  computeCategoryScore(profile1, profile2, "partner", seed+3),
-	}
+		Friend:   breakdown["friend"].HeuristicScore,
+		Coworker: breakdown["coworker"].HeuristicScore,
+		Partner:  breakdown["partner"].HeuristicScore,
+	}, breakdown
 }
 
-func calculateCategoryScore(person1, person2 models.PersonData, category string) int {
+// calculateCategoryDetails is deterministic and symmetric: the same pair gets
+// the same score in either order
+func calculateCategoryDetails(weights *scoringWeights, person1, person2 models.PersonData, category string) models.ScoreDetails {
 	profile1, ok1 := parseMBTIProfile(person1.MBTI)
 	profile2, ok2 := parseMBTIProfile(person2.MBTI)
-	seed := time.Now().UnixNano()
+	seed := noiseSeed(person1.MBTI, person2.MBTI, category)
 
+	var details models.ScoreDetails
 	if !ok1 || !ok2 {
-		return clampScore(applyNoise(3.0, seed))
+		// fallback to a neutral score with slight variation
+		details = scoreDetails("neutral", []models.ScoreContribution{}, seed)
+	} else {
+		details = computeCategoryDetails(weights, profile1, profile2, category, seed)
+	}
+	details.WeightsVersion = weights.Version
+	return details
+}
+
+// noiseSeed hashes the normalized pair and category, with the types sorted so
+// both orders of the pair get the same noise
+func noiseSeed(mbti1, mbti2, category string) int64 {
+	type1 := strings.ToUpper(strings.TrimSpace(mbti1))
+	type2 := strings.ToUpper(strings.TrimSpace(mbti2))
+	if type1 > type2 {
+		type1, type2 = type2, type1
 	}
 
-	return computeCategoryScore(profile1, profile2, category, seed)
+	hash := fnv.New64a()
+	hash.Write([]byte(type1 + "|" + type2 + "|" + category))
+	return int64(hash.Sum64())
 }
 
-func blendScores(geminiScore, heuristicScore int) int {
-	base := 0.35*float64(geminiScore) + 0.65*float64(heuristicScore)
+func blendScores(weights *scoringWeights, geminiScore, heuristicScore int) int {
+	base := weights.Blend.LLM*float64(geminiScore) + weights.Blend.Heuristic*float64(heuristicScore)
 	return clampScore(base)
 }
 
+// blendDetails blends llmScore with the heuristic score of details and returns
+// the blended score and the details with the model score and weights recorded
+func blendDetails(weights *scoringWeights, llmScore int, details models.ScoreDetails) (int, models.ScoreDetails) {
+	details.LLMScore = llmScore
+	details.Blend = &models.ScoreBlend{LLMWeight: weights.Blend.LLM, HeuristicWeight: weights.Blend.Heuristic}
+	return blendScores(weights, llmScore, details.HeuristicScore), details
+}
+
+// Confidence interval half-widths on the 0-100 scale
+const (
+	// heuristicMargin applies when there is no LLM score to compare against
+	heuristicMargin = 25.0
+	// agreementMargin applies when the heuristic and LLM scores agree exactly
+	agreementMargin = 5.0
+	// disagreementMargin widens the interval per point the scores differ by on the 1-5 scale
+	disagreementMargin = 12.5
+)
+
+// preciseScore is the unrounded score of details on 0-100, with a confidence
+// interval that widens as the heuristic and LLM scores disagree
+func preciseScore(details models.ScoreDetails) models.PreciseScore {
+	heuristic := details.Base
+	for _, contribution := range details.Contributions {
+		heuristic += contribution.Value
+	}
+	heuristic = math.Max(1, math.Min(5, heuristic+details.Noise))
+
+	if details.Blend == nil {
+		return newPreciseScore(heuristic, heuristicMargin)
+	}
+
+	llm := float64(details.LLMScore)
+	blended := details.Blend.LLMWeight*llm + details.Blend.HeuristicWeight*heuristic
+	return newPreciseScore(blended, agreementMargin+disagreementMargin*math.Abs(llm-heuristic))
+}
+
+// preciseScores computes the precise score of every category in breakdown and overall
+func preciseScores(breakdown models.ScoreBreakdown) models.PreciseScores {
+	scores := models.PreciseScores{
+		Friend:   preciseScore(breakdown["friend"]),
+		Coworker: preciseScore(breakdown["coworker"]),
+		Partner:  preciseScore(breakdown["partner"]),
+	}
+	scores.Overall = OverallPreciseScore(scores.Friend, scores.Coworker, scores.Partner)
+	return scores
+}
+
+// OverallPreciseScore averages the category precise scores and their intervals
+func OverallPreciseScore(friend, coworker, partner models.PreciseScore) models.PreciseScore {
+	return models.PreciseScore{
+		Score: roundTenth((friend.Score + coworker.Score + partner.Score) / 3),
+		Low:   roundTenth((friend.Low + coworker.Low + partner.Low) / 3),
+		High:  roundTenth((friend.High + coworker.High + partner.High) / 3),
+	}
+}
+
+// newPreciseScore maps a 1-5 score onto 0-100 with an interval of +/-margin
+// clamped to the scale
+func newPreciseScore(score, margin float64) models.PreciseScore {
+	percent := (score - 1) * 25
+	return models.PreciseScore{
+		Score: roundTenth(percent),
+		Low:   roundTenth(math.Max(0, percent-margin)),
+		High:  roundTenth(math.Min(100, percent+margin)),
+	}
+}
+
+func roundTenth(value float64) float64 {
+	return math.Round(value*10) / 10
+}
+
 type mbtiProfile struct {
 	energy    rune
 	intuition rune
@@ -81,138 +170,84 @@ func parseMBTIProfile(raw string) (mbtiProfile, bool) {
 	return profile, valid
 }
 
-func computeCategoryScore(profile1, profile2 mbtiProfile, category string, seed int64) int {
-	base := 3.0
-
-	switch category {
-	case "friend":
-		base += friendEnergyAdjustment(profile1.energy, profile2.energy)
-		base += friendIntuitionAdjustment(profile1.intuition, profile2.intuition)
-		base += friendDecisionAdjustment(profile1.decision, profile2.decision)
-		base += friendLifestyleAdjustment(profile1.lifestyle, profile2.lifestyle)
-	case "coworker":
-		base += coworkerEnergyAdjustment(profile1.energy, profile2.energy)
-		base += coworkerIntuitionAdjustment(profile1.intuition, profile2.intuition)
-		base += coworkerDecisionAdjustment(profile1.decision, profile2.decision)
-		base += coworkerLifestyleAdjustment(profile1.lifestyle, profile2.lifestyle)
-	case "partner":
-		base += partnerEnergyAdjustment(profile1.energy, profile2.energy)
-		base += partnerIntuitionAdjustment(profile1.intuition, profile2.intuition)
-		base += partnerDecisionAdjustment(profile1.decision, profile2.decision)
-		base += partnerLifestyleAdjustment(profile1.lifestyle, profile2.lifestyle)
-	default:
-		base += friendEnergyAdjustment(profile1.energy, profile2.energy)
-	}
-
-	return clampScore(applyNoise(base, seed))
-}
-
-func friendEnergyAdjustment(a, b rune) float64 {
-	if a == b {
-		return 0.4
-	}
-	return -0.2
-}
-
-func friendIntuitionAdjustment(a, b rune) float64 {
-	if a == b {
-		if a == 'N' {
-			return 0.3
-		}
-		return 0.2
-	}
-	return -0.1
-}
-
-func friendDecisionAdjustment(a, b rune) float64 {
-	if a == b {
-		if a == 'F' {
-			return 0.4
-		}
-		return 0.2
-	}
-	return 0.1
-}
+// Scoring models selectable with SCORING_MODEL
+const (
+	scoringModelLetters   = "letters"
+	scoringModelFunctions = "functions"
+)
 
-func friendLifestyleAdjustment(a, b rune) float64 {
-	if a == b {
-		return 0.2
+// activeScoringModel is set once at startup by LoadScoringModelFromEnv
+var activeScoringModel = scoringModelLetters
+
+// LoadScoringModelFromEnv selects the heuristic scoring model from SCORING_MODEL,
+// defaulting to letters, and rejects unknown models
+func LoadScoringModelFromEnv() error {
+	raw := strings.TrimSpace(os.Getenv("SCORING_MODEL"))
+	switch model := strings.ToLower(raw); model {
+	case "":
+		activeScoringModel = scoringModelLetters
+	case scoringModelLetters, scoringModelFunctions:
+		activeScoringModel = model
+	default:
+		return fmt.Errorf("unknown SCORING_MODEL %q (expected %s or %s)", raw, scoringModelLetters, scoringModelFunctions)
 	}
-	return 0.1
-}
 
-func coworkerEnergyAdjustment(a, b rune) float64 {
-	if a == b {
-		return 0.1
-	}
-	return 0.2
+	log.Printf("Using %s scoring model", activeScoringModel)
+	return nil
 }
 
-func coworkerIntuitionAdjustment(a, b rune) float64 {
-	if a == b {
-		return 0.1
+func computeCategoryDetails(weights *scoringWeights, profile1, profile2 mbtiProfile, category string, seed int64) models.ScoreDetails {
+	// Unknown categories fall back to the friend weights
+	if !contains(scoreCategories, category) {
+		category = "friend"
 	}
-	return 0.2
-}
 
-func coworkerDecisionAdjustment(a, b rune) float64 {
-	if a == b {
-		if a == 'T' {
-			return 0.4
-		}
-		return 0.2
+	model := activeScoringModel
+	var contributions []models.ScoreContribution
+	if model == scoringModelFunctions {
+		contributions = functionContributions(weights.Functions[category], profile1, profile2)
+	} else {
+		contributions = letterContributions(weights.Letters[category], profile1, profile2)
 	}
-	return 0.2
+	return scoreDetails(model, contributions, seed)
 }
 
-func coworkerLifestyleAdjustment(a, b rune) float64 {
-	if a == b {
-		if a == 'J' {
-			return 0.4
-		}
-		return 0.1
+// scoreDetails adds the contributions and seeded noise to the neutral score
+func scoreDetails(model string, contributions []models.ScoreContribution, seed int64) models.ScoreDetails {
+	score := neutralScore
+	for _, contribution := range contributions {
+		score += contribution.Value
 	}
-	return 0.1
-}
-
-func partnerEnergyAdjustment(a, b rune) float64 {
-	if a == b {
-		return -0.1
-	}
-	return 0.4
-}
-
-func partnerIntuitionAdjustment(a, b rune) float64 {
-	if a == b {
-		if a == 'N' {
-			return 0.3
-		}
-		return 0.2
+	noise := scoreNoise(seed)
+
+	return models.ScoreDetails{
+		Model:          model,
+		Base:           neutralScore,
+		Contributions:  contributions,
+		Noise:          noise,
+		HeuristicScore: clampScore(score + noise),
 	}
-	return 0.1
 }
 
-func partnerDecisionAdjustment(a, b rune) float64 {
-	if a == b {
-		if a == 'F' {
-			return 0.5
-		}
-		return 0.1
+// letterContributions scores the four MBTI letters independently
+func letterContributions(weights letterWeights, profile1, profile2 mbtiProfile) []models.ScoreContribution {
+	return []models.ScoreContribution{
+		{Dimension: "energy", Value: weights.Energy.adjustment(profile1.energy, profile2.energy)},
+		{Dimension: "intuition", Value: weights.Intuition.adjustment(profile1.intuition, profile2.intuition)},
+		{Dimension: "decision", Value: weights.Decision.adjustment(profile1.decision, profile2.decision)},
+		{Dimension: "lifestyle", Value: weights.Lifestyle.adjustment(profile1.lifestyle, profile2.lifestyle)},
 	}
-	return 0.2
 }
 
-func partnerLifestyleAdjustment(a, b rune) float64 {
-	if a == b {
-		return 0.1
+// scoreNoise is up to +/-0.3 of seeded jitter so borderline pairs do not all
+// round the same way; SCORING_NOISE=false disables it
+func scoreNoise(seed int64) float64 {
+	if !envBool("SCORING_NOISE", true) {
+		return 0
 	}
-	return 0.2
-}
 
-func applyNoise(value float64, seed int64) float64 {
 	rng := rand.New(rand.NewSource(seed))
-	noise := (rng.Float64() * 0.6) - 0.3 // [-0.3, 0.3]
-	return value + noise
+	return (rng.Float64() * 0.6) - 0.3 // [-0.3, 0.3]
 }
 
 func clampScore(value float64) int {
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"compatiblah/backend/models"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+var scoringModels = []string{scoringModelLetters, scoringModelFunctions}
+
+// scoringCase is a random pair, category and LLM score generated by
+// testing/quick. Types are mostly valid MBTI codes in mixed case with stray
+// whitespace, and sometimes garbage that takes the neutral fallback.
+type scoringCase struct {
+	Person1  models.PersonData
+	Person2  models.PersonData
+	Category string
+	LLMScore int
+}
+
+func (scoringCase) Generate(rng *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(scoringCase{
+		Person1:  models.PersonData{Name: "A", MBTI: randomMBTI(rng)},
+		Person2:  models.PersonData{Name: "B", MBTI: randomMBTI(rng)},
+		Category: scoreCategories[rng.Intn(len(scoreCategories))],
+		LLMScore: rng.Intn(5) + 1,
+	})
+}
+
+func randomMBTI(rng *rand.Rand) string {
+	if rng.Intn(10) == 0 {
+		garbage := make([]byte, rng.Intn(6))
+		for i := range garbage {
+			garbage[i] = byte('A' + rng.Intn(26))
+		}
+		return string(garbage)
+	}
+
+	letters := []string{"EI", "NS", "TF", "JP"}
+	mbti := make([]byte, len(letters))
+	for i, pair := range letters {
+		mbti[i] = pair[rng.Intn(2)]
+	}
+	value := string(mbti)
+	if rng.Intn(2) == 0 {
+		value = strings.ToLower(value)
+	}
+	if rng.Intn(4) == 0 {
+		value = " " + value + " "
+	}
+	return value
+}
+
+// checkScoringProperty runs property for every scoring model, with and without noise
+func checkScoringProperty(t *testing.T, property func(scoringCase) bool) {
+	for _, model := range scoringModels {
+		for _, noise := range []string{"true", "false"} {
+			t.Run(model+"/noise="+noise, func(t *testing.T) {
+				t.Setenv("SCORING_MODEL", model)
+				t.Setenv("SCORING_NOISE", noise)
+				if err := LoadScoringModelFromEnv(); err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { activeScoringModel = scoringModelLetters })
+				if err := quick.Check(property, &quick.Config{MaxCount: 500}); err != nil {
+					t.Error(err)
+				}
+			})
+		}
+	}
+}
+
+// TestCategoryScoreSymmetric checks that A-vs-B and B-vs-A score identically
+func TestCategoryScoreSymmetric(t *testing.T) {
+	weights := currentWeights()
+	checkScoringProperty(t, func(c scoringCase) bool {
+		return calculateCategoryDetails(weights, c.Person1, c.Person2, c.Category).HeuristicScore ==
+			calculateCategoryDetails(weights, c.Person2, c.Person1, c.Category).HeuristicScore
+	})
+}
+
+// TestCompatibilityScoresSymmetric checks symmetry of the full score set
+func TestCompatibilityScoresSymmetric(t *testing.T) {
+	weights := currentWeights()
+	checkScoringProperty(t, func(c scoringCase) bool {
+		scores1, _ := calculateCompatibilityScores(weights, c.Person1, c.Person2)
+		scores2, _ := calculateCompatibilityScores(weights, c.Person2, c.Person1)
+		return scores1 == scores2
+	})
+}
+
+// TestCategoryScoreInRange checks that every score is on the 1-5 scale
+func TestCategoryScoreInRange(t *testing.T) {
+	weights := currentWeights()
+	checkScoringProperty(t, func(c scoringCase) bool {
+		score := calculateCategoryDetails(weights, c.Person1, c.Person2, c.Category).HeuristicScore
+		return score >= 1 && score <= 5
+	})
+}
+
+// TestCategoryScoreDeterministic checks that repeated requests get the same score
+func TestCategoryScoreDeterministic(t *testing.T) {
+	weights := currentWeights()
+	checkScoringProperty(t, func(c scoringCase) bool {
+		return calculateCategoryDetails(weights, c.Person1, c.Person2, c.Category).HeuristicScore ==
+			calculateCategoryDetails(weights, c.Person1, c.Person2, c.Category).HeuristicScore
+	})
+}
+
+// TestScoreDetailsAddUp checks that the breakdown reproduces the heuristic score
+func TestScoreDetailsAddUp(t *testing.T) {
+	weights := currentWeights()
+	checkScoringProperty(t, func(c scoringCase) bool {
+		details := calculateCategoryDetails(weights, c.Person1, c.Person2, c.Category)
+		score := details.Base
+		for _, contribution := range details.Contributions {
+			score += contribution.Value
+		}
+		return details.HeuristicScore == clampScore(score+details.Noise)
+	})
+}
+
+// TestPreciseScoreInRange checks that precise scores and their intervals stay
+// on the 0-100 scale, with the score inside its interval
+func TestPreciseScoreInRange(t *testing.T) {
+	weights := currentWeights()
+	checkScoringProperty(t, func(c scoringCase) bool {
+		details := calculateCategoryDetails(weights, c.Person1, c.Person2, c.Category)
+		_, blended := blendDetails(weights, c.LLMScore, details)
+
+		for _, precise := range []models.PreciseScore{preciseScore(details), preciseScore(blended)} {
+			if precise.Low < 0 || precise.Low > precise.Score || precise.Score > precise.High || precise.High > 100 {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// TestBlendScoresInRange checks that blending two 1-5 scores stays on the scale
+// and between them
+func TestBlendScoresInRange(t *testing.T) {
+	weights := currentWeights()
+	property := func(llm, heuristic uint8) bool {
+		a, b := int(llm%5)+1, int(heuristic%5)+1
+		blended := blendScores(weights, a, b)
+		return blended >= min(a, b) && blended <= max(a, b)
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestNoiseDisabled checks that SCORING_NOISE=false scores the unperturbed base
+func TestNoiseDisabled(t *testing.T) {
+	t.Setenv("SCORING_NOISE", "false")
+	weights := currentWeights()
+	person1 := models.PersonData{Name: "A", MBTI: "INTJ"}
+	person2 := models.PersonData{Name: "B", MBTI: "ENFP"}
+
+	// 3 - 0.2 (energy) + 0.3 (intuition) + 0.1 (decision) + 0.1 (lifestyle)
+	if got := calculateCategoryDetails(weights, person1, person2, "friend").HeuristicScore; got != 3 {
+		t.Errorf("friend score = %d, want 3", got)
+	}
+	// 3 + 0.4 (energy) + 0.3 (intuition) + 0.2 (decision) + 0.2 (lifestyle)
+	if got := calculateCategoryDetails(weights, person1, person2, "partner").HeuristicScore; got != 4 {
+		t.Errorf("partner score = %d, want 4", got)
+	}
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"compatiblah/backend/models"
+	"context"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Kinds of StreamEvent
+const (
+	StreamEventScore   = "score"
+	StreamEventSection = "section"
+)
+
+// StreamEvent is a partial result emitted while a category assessment streams in.
+// The score always arrives before any section.
+type StreamEvent struct {
+	Kind    string
+	Score   int
+	Index   int
+	Section models.ExplanationSection
+}
+
+// StreamCategoryCompatibility assesses one category like AssessCategoryCompatibility,
+// calling emit with the blended score as soon as the model has produced it and with
+// each explanation section once it is complete. Streams are not coalesced because
+// every caller needs its own progress events. Samples in options is ignored.
+// Scores use the weights pinned in ctx by WithWeightsSnapshot, if any.
+func StreamCategoryCompatibility(ctx context.Context, person1, person2 models.PersonData, category string, options AssessmentOptions, emit func(StreamEvent)) (*CategoryResponse, error) {
+	if err := validateInput(person1, person2, category); err != nil {
+		return nil, err
+	}
+
+	options, err := NormalizeOptions(options)
+	if err != nil {
+		return nil, err
+	}
+
+	weights := weightsFor(ctx)
+
+	cache := newResponseCache(person1, person2, category, options)
+	if cached, ok := cache.loadCategory(); ok {
+		response := categoryResponseFromPayload(weights, person1, person2, category, cached)
+		emitCategoryResponse(response, emit)
+		return response, nil
+	}
+
+	// A section that fails the output check stops the generation
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Computed once so the streamed score matches the final one
+	details := calculateCategoryDetails(weights, person1, person2, category)
+	stream := &categoryStream{weights: weights, heuristic: details.HeuristicScore, emit: emit, cancel: cancel}
+
+	prompt, err := buildCategoryPrompt(person1, person2, category, options)
+	if err != nil {
+		return nil, err
+	}
+
+	generated, err := generateStream(ctx, GenerationRequest{
+		Prompt:          prompt,
+		Schema:          structuredSchema(categorySchema),
+		Category:        category,
+		MaxOutputTokens: options.maxOutputTokens(1),
+	}, stream.feed)
+	if stream.rejected != nil {
+		return nil, stream.rejected
+	}
+	if providerUnavailable(err) {
+		response := heuristicCategoryResponse(weights, person1, person2, category, options.Language)
+		emitCategoryResponse(response, emit)
+		return response, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := decodeCategoryPayload(generated, category, options.Language)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.storeCategory(payload)
+
+	score, details := blendDetails(weights, validModelScore(payload.Score), details)
+	response := &CategoryResponse{
+		Score:          score,
+		Explanation:    payload.Explanation,
+		Source:         llmSource(),
+		PromptVersion:  promptVersion(promptCategory),
+		WeightsVersion: weights.Version,
+		ScoreDetails:   details,
+		PreciseScore:   preciseScore(details),
+	}
+	stream.finish(response)
+	return response, nil
+}
+
+// emitCategoryResponse replays a complete response as stream events
+func emitCategoryResponse(response *CategoryResponse, emit func(StreamEvent)) {
+	stream := &categoryStream{emit: emit}
+	stream.finish(response)
+}
+
+var streamScorePattern = regexp.MustCompile(`"score"\s*:\s*(-?\d+)\s*[,}]`)
+var streamSectionsPattern = regexp.MustCompile(`"sections"\s*:\s*\[`)
+
+// categoryStream incrementally parses model output for a category payload and
+// emits the score and sections as soon as they are complete. Sections pass the
+// output check before they are emitted; the first failure cancels the generation.
+type categoryStream struct {
+	weights      *scoringWeights
+	heuristic    int
+	emit         func(StreamEvent)
+	cancel       context.CancelFunc
+	text         strings.Builder
+	scoreSent    bool
+	sectionsSent int
+	rejected     error
+}
+
+// feed appends a chunk of model output and emits anything newly complete
+func (s *categoryStream) feed(chunk string) {
+	if s.rejected != nil {
+		return
+	}
+	s.text.WriteString(chunk)
+	text := s.text.String()
+
+	if !s.scoreSent {
+		// Only look before the explanation so a "score" inside bullet text is ignored
+		head := text
+		if i := strings.Index(head, `"explanation"`); i >= 0 {
+			head = head[:i]
+		}
+		match := streamScorePattern.FindStringSubmatch(head)
+		if match == nil {
+			// Sections wait until the score is known
+			return
+		}
+		score, _ := strconv.Atoi(match[1])
+		s.sendScore(blendScores(s.weights, validModelScore(score), s.heuristic))
+	}
+
+	sections := completeSections(text)
+	if len(sections) <= s.sectionsSent {
+		return
+	}
+	if err := checkExplanationOutput(models.CategoryExplanation{Sections: sections}); err != nil {
+		s.rejected = err
+		s.cancel()
+		return
+	}
+	for _, section := range sections[s.sectionsSent:] {
+		s.sendSection(section)
+	}
+}
+
+// finish emits whatever the stream has not delivered yet from the final response
+func (s *categoryStream) finish(response *CategoryResponse) {
+	if !s.scoreSent {
+		s.sendScore(response.Score)
+	}
+	if s.sectionsSent < len(response.Explanation.Sections) {
+		for _, section := range response.Explanation.Sections[s.sectionsSent:] {
+			s.sendSection(section)
+		}
+	}
+}
+
+func (s *categoryStream) sendScore(score int) {
+	s.scoreSent = true
+	s.emit(StreamEvent{Kind: StreamEventScore, Score: score})
+}
+
+func (s *categoryStream) sendSection(section models.ExplanationSection) {
+	s.emit(StreamEvent{Kind: StreamEventSection, Index: s.sectionsSent, Section: section})
+	s.sectionsSent++
+}
+
+// completeSections decodes every fully received object in the "sections" array of
+// a partial JSON document. Objects that fail to decode end the scan.
+func completeSections(text string) []models.ExplanationSection {
+	loc := streamSectionsPattern.FindStringIndex(text)
+	if loc == nil {
+		return nil
+	}
+
+	var sections []models.ExplanationSection
+	rest := text[loc[1]:]
+	for {
+		rest = strings.TrimLeft(rest, " \t\r\n,")
+		if rest == "" || rest[0] != '{' {
+			return sections
+		}
+
+		end := matchingBrace(rest)
+		if end < 0 {
+			return sections
+		}
+
+		var section models.ExplanationSection
+		if err := json.Unmarshal([]byte(rest[:end+1]), &section); err != nil {
+			return sections
+		}
+		sections = append(sections, section)
+		rest = rest[end+1:]
+	}
+}
+
+// matchingBrace returns the index of the brace closing the object that starts at
+// text[0], skipping braces inside strings, or -1 if the object is incomplete
+func matchingBrace(text string) int {
+	depth := 0
+	inString := false
+	escaped := false
+
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case ch == '\\':
+				escaped = true
+			case ch == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch ch {
+		case '"':
+			inString = true
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"compatiblah/backend/db"
+	"compatiblah/backend/models"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+)
+
+// TokenUsage is the token count reported for one generation
+type TokenUsage struct {
+	PromptTokens    int
+	CandidateTokens int
+	TotalTokens     int
+}
+
+// modelPrice is the list price in USD per million input and output tokens
+type modelPrice struct {
+	input  float64
+	output float64
+}
+
+// modelPricing holds list prices for known models. Unknown and local models are
+// costed at zero unless LLM_INPUT_PRICE_PER_MTOK / LLM_OUTPUT_PRICE_PER_MTOK are set.
+var modelPricing = map[string]modelPrice{
+	"gemini-2.0-flash":      {input: 0.10, output: 0.40},
+	"gemini-2.0-flash-lite": {input: 0.075, output: 0.30},
+	"gemini-2.5-flash":      {input: 0.30, output: 2.50},
+	"gemini-2.5-pro":        {input: 1.25, output: 10.00},
+	"gemini-1.5-flash":      {input: 0.075, output: 0.30},
+	"gemini-1.5-pro":        {input: 1.25, output: 5.00},
+	"gpt-4o-mini":           {input: 0.15, output: 0.60},
+	"gpt-4o":                {input: 2.50, output: 10.00},
+}
+
+// priceFor returns the configured or list price for model
+func priceFor(model string) modelPrice {
+	price := modelPricing[strings.ToLower(model)]
+	price.input = envFloat("LLM_INPUT_PRICE_PER_MTOK", price.input)
+	price.output = envFloat("LLM_OUTPUT_PRICE_PER_MTOK", price.output)
+	return price
+}
+
+// estimateCost returns the USD cost of usage on model
+func estimateCost(model string, usage TokenUsage) float64 {
+	price := priceFor(model)
+	return (float64(usage.PromptTokens)*price.input + float64(usage.CandidateTokens)*price.output) / 1e6
+}
+
+// UsageTracker collects the usage rows recorded while serving one request so they
+// can be attributed to the assessment once it has an ID
+type UsageTracker struct {
+	mu  sync.Mutex
+	ids []int64
+}
+
+type usageTrackerKey struct{}
+
+// WithUsageTracker returns a context whose LLM calls are collected by the returned tracker
+func WithUsageTracker(ctx context.Context) (context.Context, *UsageTracker) {
+	tracker := &UsageTracker{}
+	return context.WithValue(ctx, usageTrackerKey{}, tracker), tracker
+}
+
+// Attribute links every call tracked so far to the saved assessment
+func (t *UsageTracker) Attribute(assessmentID string) {
+	t.mu.Lock()
+	ids := append([]int64(nil), t.ids...)
+	t.mu.Unlock()
+
+	if err := db.AttributeUsage(ids, assessmentID); err != nil {
+		log.Printf("Failed to attribute LLM usage to assessment %s: %v", assessmentID, err)
+	}
+}
+
+func (t *UsageTracker) add(id int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ids = append(t.ids, id)
+}
+
+// billedError is a failed call whose response still reported token usage, e.g.
+// a safety block or a stream that broke off, so the usage can be recorded
+type billedError struct {
+	err   error
+	usage TokenUsage
+}
+
+func (e *billedError) Error() string { return e.err.Error() }
+
+func (e *billedError) Unwrap() error { return e.err }
+
+// withUsage attaches the usage reported by a failed call to err; zero usage
+// leaves err unchanged
+func withUsage(err error, usage TokenUsage) error {
+	if err == nil || usage == (TokenUsage{}) {
+		return err
+	}
+	return &billedError{err: err, usage: usage}
+}
+
+// recordFailedUsage records the usage attached to err by withUsage, if any
+func recordFailedUsage(ctx context.Context, provider LLMProvider, req GenerationRequest, err error) {
+	var billed *billedError
+	if errors.As(err, &billed) {
+		recordUsage(ctx, provider, req, billed.usage)
+	}
+}
+
+// recordUsage persists the token usage of a call. Every call that reports usage
+// is recorded, including failed ones and ones whose output later fails to
+// parse, since they are billed either way.
+func recordUsage(ctx context.Context, provider LLMProvider, req GenerationRequest, usage TokenUsage) {
+	incMetricBy("prompt_tokens", usage.PromptTokens)
+	incMetricBy("candidate_tokens", usage.CandidateTokens)
+
+	if db.DB == nil {
+		return
+	}
+
+	category := req.Category
+	if category == "" {
+		category = "unknown"
+	}
+
+	id, err := db.SaveUsage(&models.UsageRecord{
+		Category:        category,
+		Provider:        provider.Name(),
+		Model:           provider.Model(),
+		PromptTokens:    usage.PromptTokens,
+		CandidateTokens: usage.CandidateTokens,
+		TotalTokens:     usage.TotalTokens,
+		CostUSD:         estimateCost(provider.Model(), usage),
+	})
+	if err != nil {
+		// Accounting failures never fail the assessment
+		log.Printf("Failed to record LLM usage: %v", err)
+		return
+	}
+
+	if tracker, ok := ctx.Value(usageTrackerKey{}).(*UsageTracker); ok {
+		tracker.add(id)
+	}
+}
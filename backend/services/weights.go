@@ -0,0 +1,250 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//go:embed weights.json
+var embeddedWeights []byte
+
+// scoreCategories are the categories every weights file must cover
+var scoreCategories = []string{"friend", "coworker", "partner"}
+
+// maxAdjustment bounds every score adjustment so a typo cannot swamp the 1-5 scale
+const maxAdjustment = 2.0
+
+// scoringWeights are the tunable numbers of the heuristic scoring models and
+// the blend with the LLM score, loaded from weights.json or SCORING_WEIGHTS_FILE
+type scoringWeights struct {
+	// Version is recorded on every assessment scored with these weights
+	Version   string                     `json:"version"`
+	Blend     blendWeights               `json:"blend"`
+	Letters   map[string]letterWeights   `json:"letters"`
+	Functions map[string]functionWeights `json:"functions"`
+}
+
+// blendWeights split a blended score between the LLM and heuristic scores
+type blendWeights struct {
+	LLM       float64 `json:"llm"`
+	Heuristic float64 `json:"heuristic"`
+}
+
+// letterWeights are the per-category adjustments of the letters scoring model
+type letterWeights struct {
+	Energy    dimensionWeights `json:"energy"`
+	Intuition dimensionWeights `json:"intuition"`
+	Decision  dimensionWeights `json:"decision"`
+	Lifestyle dimensionWeights `json:"lifestyle"`
+}
+
+// dimensionWeights adjust a score by whether two types share an MBTI letter
+type dimensionWeights struct {
+	// Same is keyed by the shared letter, e.g. "N" or "S"
+	Same      map[string]float64 `json:"same"`
+	Different float64            `json:"different"`
+}
+
+func (d dimensionWeights) adjustment(a, b rune) float64 {
+	if a == b {
+		return d.Same[string(a)]
+	}
+	return d.Different
+}
+
+var (
+	weightsMu sync.RWMutex
+	weights   = mustParseWeights(embeddedWeights)
+)
+
+// LoadWeightsFromEnv replaces the scoring weights with SCORING_WEIGHTS_FILE, or
+// the embedded defaults when it is unset, and returns the new version. Invalid
+// weights are rejected and the current ones stay active.
+func LoadWeightsFromEnv() (string, error) {
+	raw, source := embeddedWeights, "embedded weights"
+	if path := strings.TrimSpace(os.Getenv("SCORING_WEIGHTS_FILE")); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read scoring weights: %w", err)
+		}
+		raw, source = data, path
+	}
+
+	loaded, err := parseWeights(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid scoring weights in %s: %w", source, err)
+	}
+
+	weightsMu.Lock()
+	weights = loaded
+	weightsMu.Unlock()
+
+	log.Printf("Using scoring weights %s from %s", loaded.Version, source)
+	return loaded.Version, nil
+}
+
+// WeightsVersion returns the version of the active scoring weights
+func WeightsVersion() string {
+	return currentWeights().Version
+}
+
+// currentWeights returns the active weights; callers keep the snapshot for a
+// whole assessment so a reload cannot mix two versions in one result
+func currentWeights() *scoringWeights {
+	weightsMu.RLock()
+	defer weightsMu.RUnlock()
+	return weights
+}
+
+type weightsSnapshotKey struct{}
+
+// WithWeightsSnapshot returns a context that pins the active weights, so every
+// category scored under it uses the same version even if the weights are reloaded
+func WithWeightsSnapshot(ctx context.Context) context.Context {
+	return context.WithValue(ctx, weightsSnapshotKey{}, currentWeights())
+}
+
+// weightsFor returns the weights pinned in ctx, or the active ones
+func weightsFor(ctx context.Context) *scoringWeights {
+	if snapshot, ok := ctx.Value(weightsSnapshotKey{}).(*scoringWeights); ok {
+		return snapshot
+	}
+	return currentWeights()
+}
+
+func mustParseWeights(raw []byte) *scoringWeights {
+	parsed, err := parseWeights(raw)
+	if err != nil {
+		panic(fmt.Errorf("embedded scoring weights: %w", err))
+	}
+	return parsed
+}
+
+// parseWeights decodes and validates a weights file, rejecting unknown fields
+func parseWeights(raw []byte) (*scoringWeights, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+
+	var parsed scoringWeights
+	if err := decoder.Decode(&parsed); err != nil {
+		return nil, err
+	}
+	if err := parsed.validate(); err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+func (w *scoringWeights) validate() error {
+	if strings.TrimSpace(w.Version) == "" {
+		return errors.New("version is required")
+	}
+
+	if w.Blend.LLM < 0 || w.Blend.Heuristic < 0 {
+		return errors.New("blend weights must not be negative")
+	}
+	if math.Abs(w.Blend.LLM+w.Blend.Heuristic-1) > 1e-6 {
+		return fmt.Errorf("blend weights must sum to 1, got %g", w.Blend.LLM+w.Blend.Heuristic)
+	}
+
+	if err := checkCategories("letters", sortedKeys(w.Letters)); err != nil {
+		return err
+	}
+	if err := checkCategories("functions", sortedKeys(w.Functions)); err != nil {
+		return err
+	}
+
+	for _, category := range scoreCategories {
+		letters := w.Letters[category]
+		dimensions := []struct {
+			name    string
+			weights dimensionWeights
+			letters string
+		}{
+			{"energy", letters.Energy, "EI"},
+			{"intuition", letters.Intuition, "NS"},
+			{"decision", letters.Decision, "TF"},
+			{"lifestyle", letters.Lifestyle, "JP"},
+		}
+		for _, dimension := range dimensions {
+			path := "letters." + category + "." + dimension.name
+			if len(dimension.weights.Same) != len(dimension.letters) {
+				return fmt.Errorf("%s.same must have exactly the letters %s", path, strings.Join(strings.Split(dimension.letters, ""), " and "))
+			}
+			for _, letter := range dimension.letters {
+				value, ok := dimension.weights.Same[string(letter)]
+				if !ok {
+					return fmt.Errorf("%s.same is missing %c", path, letter)
+				}
+				if err := checkAdjustment(fmt.Sprintf("%s.same.%c", path, letter), value); err != nil {
+					return err
+				}
+			}
+			if err := checkAdjustment(path+".different", dimension.weights.Different); err != nil {
+				return err
+			}
+		}
+
+		functions := w.Functions[category]
+		adjustments := []struct {
+			name  string
+			value float64
+		}{
+			{"same_dominant", functions.SameDominant},
+			{"mirrored", functions.Mirrored},
+			{"supports", functions.Supports},
+			{"blind_spot", functions.BlindSpot},
+			{"same_perceiving", functions.SamePerceiving},
+			{"perceiving_gap", functions.PerceivingGap},
+			{"same_judging", functions.SameJudging},
+			{"judging_gap", functions.JudgingGap},
+		}
+		for _, adjustment := range adjustments {
+			if err := checkAdjustment("functions."+category+"."+adjustment.name, adjustment.value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkCategories requires exactly the score categories in a section
+func checkCategories(section string, categories []string) error {
+	for _, category := range categories {
+		if !contains(scoreCategories, category) {
+			return fmt.Errorf("%s has unknown category %q", section, category)
+		}
+	}
+	for _, category := range scoreCategories {
+		if !contains(categories, category) {
+			return fmt.Errorf("%s is missing category %q", section, category)
+		}
+	}
+	return nil
+}
+
+func checkAdjustment(path string, value float64) error {
+	if math.IsNaN(value) || math.Abs(value) > maxAdjustment {
+		return fmt.Errorf("%s must be between %g and %g", path, -maxAdjustment, maxAdjustment)
+	}
+	return nil
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}